@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 	"subscription-tracker/internal/config"
 	"subscription-tracker/internal/database"
 	"subscription-tracker/internal/server"
+	"subscription-tracker/internal/worker"
 
 	"github.com/joho/godotenv"
 )
@@ -21,6 +23,15 @@ func main() {
 	// Initialize database
 	db := database.InitDB(cfg)
 
+	// Run background jobs in-process unless a dedicated worker is deployed
+	if cfg.Worker.Enabled {
+		runner, err := worker.New(db, cfg)
+		if err != nil {
+			log.Fatal("Failed to initialize background jobs:", err)
+		}
+		go runner.Run(context.Background())
+	}
+
 	// Create and start server
 	srv := server.New(db, cfg)
 
@@ -0,0 +1,76 @@
+// Command fximport loads exchange rates from an ECB eurofxref XML or CSV file
+// into the database without depending on a live rates API.
+//
+//	go run ./cmd/fximport -file eurofxref-hist.xml
+//	go run ./cmd/fximport -file rates.csv -source "treasury 2024-05"
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"subscription-tracker/internal/config"
+	"subscription-tracker/internal/database"
+	"subscription-tracker/internal/fxrates"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/services"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	path := flag.String("file", "", "rates file to import, or - for standard input")
+	format := flag.String("format", "", "file format: ecb or csv (default: guessed from the file name)")
+	source := flag.String("source", "", "source recorded for audit (default: the file name)")
+	flag.Parse()
+
+	if *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load environment variables from .env file in development
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found or error loading it")
+	}
+
+	var file io.Reader = os.Stdin
+	filename := "stdin"
+	if *path != "-" {
+		opened, err := os.Open(*path)
+		if err != nil {
+			log.Fatal("Failed to open rates file:", err)
+		}
+		defer opened.Close()
+		file = opened
+		filename = filepath.Base(*path)
+	}
+
+	rateFormat := fxrates.Format(*format)
+	if rateFormat == "" {
+		rateFormat = fxrates.DetectFormat(filename)
+	}
+	if *source == "" {
+		*source = filename
+	}
+
+	cfg := config.Load()
+	db := database.InitDB(cfg)
+
+	exchangeRateService := services.NewExchangeRateService(
+		repository.NewExchangeRateRepository(db),
+		repository.NewCurrencyRepository(db),
+	)
+
+	rateImport, err := exchangeRateService.Import(rateFormat, *source, file, nil)
+	if err != nil {
+		log.Fatal("Import failed: ", err)
+	}
+
+	log.Printf("Imported %d rates from %s (%d skipped)", rateImport.RateCount, rateImport.Source, rateImport.SkippedCount)
+	if rateImport.UnknownCurrencies != "" {
+		log.Printf("Skipped currencies not in the currencies table: %s", rateImport.UnknownCurrencies)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"subscription-tracker/internal/config"
+	"subscription-tracker/internal/database"
+	"subscription-tracker/internal/worker"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables from .env file in development
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found or error loading it")
+	}
+
+	// Load configuration
+	cfg := config.Load()
+
+	// Initialize database
+	db := database.InitDB(cfg)
+
+	runner, err := worker.New(db, cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize background jobs:", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Println("Worker starting")
+	runner.Run(ctx)
+	log.Println("Worker stopped")
+}
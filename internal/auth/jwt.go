@@ -16,27 +16,36 @@ var (
 )
 
 type Claims struct {
-	UserID models.ULID `json:"user_id"`
-	Email  string      `json:"email"`
+	UserID       models.ULID `json:"user_id"`
+	Email        string      `json:"email"`
+	TokenVersion int         `json:"ver"` // The user's TokenVersion when issued
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(user *models.User, cfg *config.Config) (string, error) {
+// GenerateToken issues a short-lived access token for user and returns it
+// with its expiry. Longer sessions are kept alive with refresh tokens.
+func GenerateToken(user *models.User, cfg *config.Config) (string, time.Time, error) {
 	secretKey := []byte(cfg.JWT.SecretKey)
-	expirationHours := cfg.JWT.ExpirationHours
+	now := time.Now()
+	expiresAt := now.Add(cfg.JWT.AccessTokenTTL)
 
 	claims := Claims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:       user.ID,
+		Email:        user.Email,
+		TokenVersion: user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expirationHours))),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secretKey)
+	signed, err := token.SignedString(secretKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
 }
 
 func ValidateToken(tokenString string, cfg *config.Config) (*Claims, error) {
@@ -0,0 +1,135 @@
+// Package calendar renders subscriptions as an RFC 5545 iCalendar feed.
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"subscription-tracker/internal/models"
+)
+
+const (
+	productID   = "-//Subscription Tracker//Renewals//EN"
+	maxLineSize = 75 // octets, excluding the CRLF
+)
+
+// Event is an all-day, optionally recurring calendar event.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	RRule       string // Recurrence rule without the "RRULE:" prefix
+	AlarmDays   int    // Days before Start to alert; 0 disables the alarm
+}
+
+// Render writes a VCALENDAR containing events. stamp is used as DTSTAMP.
+func Render(name string, events []Event, stamp time.Time) []byte {
+	var b builder
+	b.line("BEGIN:VCALENDAR")
+	b.line("VERSION:2.0")
+	b.line("PRODID:" + productID)
+	b.line("CALSCALE:GREGORIAN")
+	b.line("METHOD:PUBLISH")
+	b.line("X-WR-CALNAME:" + escapeText(name))
+
+	for _, event := range events {
+		b.line("BEGIN:VEVENT")
+		b.line("UID:" + event.UID)
+		b.line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+		b.line("DTSTART;VALUE=DATE:" + event.Start.Format("20060102"))
+		b.line("SUMMARY:" + escapeText(event.Summary))
+		if event.Description != "" {
+			b.line("DESCRIPTION:" + escapeText(event.Description))
+		}
+		if event.RRule != "" {
+			b.line("RRULE:" + event.RRule)
+		}
+		b.line("TRANSP:TRANSPARENT")
+		if event.AlarmDays > 0 {
+			b.line("BEGIN:VALARM")
+			b.line("ACTION:DISPLAY")
+			b.line("DESCRIPTION:" + escapeText(event.Summary))
+			b.line(fmt.Sprintf("TRIGGER:-P%dD", event.AlarmDays))
+			b.line("END:VALARM")
+		}
+		b.line("END:VEVENT")
+	}
+
+	b.line("END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// RecurrenceRule derives an RRULE from a billing cycle. Month and year based
+// cycles whose anchor day does not exist in every month pick the last
+// available day, matching models.BillingCycle.NextDate.
+func RecurrenceRule(cycle models.BillingCycle, start time.Time, anchorDay int) string {
+	interval := cycle.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	rule := "INTERVAL=" + strconv.Itoa(interval)
+
+	switch cycle.Unit {
+	case models.BillingCycleUnitDay:
+		return "FREQ=DAILY;" + rule
+	case models.BillingCycleUnitWeek:
+		return "FREQ=WEEKLY;" + rule
+	case models.BillingCycleUnitYear:
+		return "FREQ=YEARLY;" + rule + ";BYMONTH=" + strconv.Itoa(int(start.Month())) + byMonthDay(anchorDay)
+	default:
+		return "FREQ=MONTHLY;" + rule + byMonthDay(anchorDay)
+	}
+}
+
+func byMonthDay(anchorDay int) string {
+	if anchorDay <= 28 {
+		return ";BYMONTHDAY=" + strconv.Itoa(anchorDay)
+	}
+	// Candidate days from 28 up to the anchor; BYSETPOS=-1 keeps the latest
+	// one that exists in the month.
+	days := make([]string, 0, anchorDay-27)
+	for day := 28; day <= anchorDay; day++ {
+		days = append(days, strconv.Itoa(day))
+	}
+	return ";BYMONTHDAY=" + strings.Join(days, ",") + ";BYSETPOS=-1"
+}
+
+// escapeText escapes a TEXT property value (RFC 5545 section 3.3.11).
+func escapeText(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`;`, `\;`,
+		`,`, `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return replacer.Replace(s)
+}
+
+type builder struct {
+	strings.Builder
+}
+
+// line writes a content line, folding it at 75 octets without splitting
+// multi-byte characters (RFC 5545 section 3.1).
+func (b *builder) line(s string) {
+	limit := maxLineSize
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space that counts toward the limit
+		limit = maxLineSize - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
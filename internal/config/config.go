@@ -5,12 +5,17 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	JWT      JWTConfig
+	Worker   WorkerConfig
+	Notifier NotifierConfig
+	Account  AccountConfig
 }
 
 type ServerConfig struct {
@@ -30,7 +35,48 @@ type DatabaseConfig struct {
 
 type JWTConfig struct {
 	SecretKey       string
-	ExpirationHours int
+	AccessTokenTTL  time.Duration // Lifetime of access tokens; keep short as they can't be revoked individually
+	RefreshTokenTTL time.Duration // Refresh tokens expire after this long without being used
+}
+
+type WorkerConfig struct {
+	Enabled              bool // Run background jobs inside the API process
+	RenewalInterval      time.Duration
+	ReminderInterval     time.Duration
+	ExportInterval       time.Duration // How often requested data exports are built
+	PurgeInterval        time.Duration // How often deleted accounts past their grace period are purged
+	TokenCleanupInterval time.Duration // How often expired refresh tokens are deleted
+}
+
+type AccountConfig struct {
+	// How long a deleted account can still be restored by logging in
+	// before it and all its data are purged.
+	DeletionGracePeriod time.Duration
+	AdminEmails         []string // Users with these emails are made administrators, lowercased
+}
+
+// IsAdminEmail reports whether email belongs to a configured administrator.
+func (c *AccountConfig) IsAdminEmail(email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	for _, adminEmail := range c.AdminEmails {
+		if adminEmail == email {
+			return true
+		}
+	}
+	return false
+}
+
+type NotifierConfig struct {
+	Driver string // Valid values: log, noop, smtp
+	SMTP   SMTPConfig
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string // Leave empty to send without authentication
+	Password string
+	From     string
 }
 
 // Load initializes configuration from environment variables
@@ -43,7 +89,30 @@ func Load() *Config {
 		Database: loadDatabaseConfig(),
 		JWT: JWTConfig{
 			SecretKey:       getEnvOrDefault("JWT_SECRET_KEY", "your-secret-key"),
-			ExpirationHours: getEnvAsIntOrDefault("JWT_EXPIRATION_HOURS", 24),
+			AccessTokenTTL:  getEnvAsDurationOrDefault("JWT_ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL: getEnvAsDurationOrDefault("JWT_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		},
+		Worker: WorkerConfig{
+			Enabled:              getEnvAsBoolOrDefault("WORKER_ENABLED", true),
+			RenewalInterval:      getEnvAsDurationOrDefault("RENEWAL_INTERVAL", time.Hour),
+			ReminderInterval:     getEnvAsDurationOrDefault("REMINDER_INTERVAL", 15*time.Minute),
+			ExportInterval:       getEnvAsDurationOrDefault("EXPORT_INTERVAL", time.Minute),
+			PurgeInterval:        getEnvAsDurationOrDefault("PURGE_INTERVAL", time.Hour),
+			TokenCleanupInterval: getEnvAsDurationOrDefault("TOKEN_CLEANUP_INTERVAL", time.Hour),
+		},
+		Notifier: NotifierConfig{
+			Driver: getEnvOrDefault("NOTIFIER", "log"),
+			SMTP: SMTPConfig{
+				Host:     getEnvOrDefault("SMTP_HOST", "localhost"),
+				Port:     getEnvOrDefault("SMTP_PORT", "1025"),
+				Username: os.Getenv("SMTP_USERNAME"),
+				Password: os.Getenv("SMTP_PASSWORD"),
+				From:     getEnvOrDefault("SMTP_FROM", "Subscription Tracker <no-reply@localhost>"),
+			},
+		},
+		Account: AccountConfig{
+			DeletionGracePeriod: getEnvAsDurationOrDefault("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+			AdminEmails:         getEnvAsList("ADMIN_EMAILS"),
 		},
 	}
 
@@ -87,12 +156,34 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
-func getEnvAsIntOrDefault(key string, defaultValue int) int {
+// getEnvAsList splits a comma-separated variable into lowercased,
+// trimmed entries, skipping empty ones.
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvAsBoolOrDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+		log.Printf("Warning: Invalid boolean value for %s, using default: %t", key, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvAsDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			return duration
 		}
-		log.Printf("Warning: Invalid integer value for %s, using default: %d", key, defaultValue)
+		log.Printf("Warning: Invalid duration value for %s, using default: %s", key, defaultValue)
 	}
 	return defaultValue
 }
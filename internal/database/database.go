@@ -38,6 +38,7 @@ func InitDB(cfg *config.Config) *gorm.DB {
 
 	// Auto-migrate the schema
 	log.Println("Starting database migration...")
+	runSchemaPreparations(db)
 	err = db.AutoMigrate(
 		&models.User{},
 		&models.Category{},
@@ -45,20 +46,49 @@ func InitDB(cfg *config.Config) *gorm.DB {
 		&models.PaymentMethod{},
 		&models.BillingCycle{},
 		&models.Subscription{},
+		&models.Payment{},
+		&models.CalendarToken{},
+		&models.Reminder{},
+		&models.PriceChange{},
+		&models.Cancellation{},
+		&models.PauseWindow{},
+		&models.ExchangeRate{},
+		&models.ExchangeRateImport{},
+		&models.Budget{},
+		&models.DataExport{},
+		&models.RefreshToken{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	runDataMigrations(db)
 	log.Println("Database migration completed successfully")
 
 	// Seed default data
 	log.Println("Starting to seed default data...")
 	seedDefaultData(db)
+	grantAdmins(db, cfg.Account.AdminEmails)
 	log.Println("Default data seeding completed")
 
 	return db
 }
 
+// grantAdmins makes the users with the configured administrator emails
+// administrators. Users registering later are granted on registration.
+// Administrators are never demoted here, so removing an email from the list
+// requires clearing is_admin by hand.
+func grantAdmins(db *gorm.DB, emails []string) {
+	if len(emails) == 0 {
+		return
+	}
+	err := db.Model(&models.User{}).
+		Where("email IN ? AND NOT is_admin", emails).
+		Update("is_admin", true).Error
+	if err != nil {
+		log.Println("Failed to grant administrators:", err)
+	}
+}
+
 func seedDefaultData(db *gorm.DB) {
 	// Seed default categories if they don't exist
 	for _, category := range models.DefaultCategories {
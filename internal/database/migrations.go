@@ -0,0 +1,161 @@
+package database
+
+import (
+	"log"
+
+	"subscription-tracker/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// runSchemaPreparations fixes up rows that would keep AutoMigrate from
+// adding constraints. Each step must be safe to run on every startup.
+func runSchemaPreparations(db *gorm.DB) {
+	if err := removeDuplicateBudgets(db); err != nil {
+		log.Fatal("Failed to remove duplicate budgets:", err)
+	}
+}
+
+// removeDuplicateBudgets soft-deletes all but the latest budget of each
+// category, so the unique index on budgets can be created.
+func removeDuplicateBudgets(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Budget{}) {
+		return nil
+	}
+	return db.Exec(`
+		UPDATE budgets SET deleted_at = NOW()
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id, category_id ORDER BY created_at DESC) AS position
+				FROM budgets WHERE deleted_at IS NULL
+			) ranked
+			WHERE position > 1
+		)`).Error
+}
+
+// runDataMigrations converts rows written by older schema versions. Each step
+// must be safe to run on every startup.
+func runDataMigrations(db *gorm.DB) {
+	if err := migrateBillingCycleDays(db); err != nil {
+		log.Fatal("Failed to migrate billing cycles:", err)
+	}
+	if err := migrateSubscriptionActiveFlag(db); err != nil {
+		log.Fatal("Failed to migrate subscription statuses:", err)
+	}
+	if err := backfillBillingAnchorDays(db); err != nil {
+		log.Fatal("Failed to backfill billing anchor days:", err)
+	}
+	if err := backfillPriceHistory(db); err != nil {
+		log.Fatal("Failed to backfill price history:", err)
+	}
+}
+
+// migrateBillingCycleDays converts the legacy fixed day count on billing
+// cycles into an interval and calendar unit, then drops the days column.
+func migrateBillingCycleDays(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.BillingCycle{}, "days") {
+		return nil
+	}
+
+	log.Println("Converting billing cycle day counts to calendar units...")
+	return db.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			ID   models.ULID
+			Days int
+		}
+		if err := tx.Table("billing_cycles").Select("id, days").Scan(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			interval, unit := intervalFromDays(row.Days)
+			err := tx.Table("billing_cycles").
+				Where("id = ?", row.ID).
+				Updates(map[string]interface{}{"interval_count": interval, "unit": unit}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Migrator().DropColumn(&models.BillingCycle{}, "days")
+	})
+}
+
+// intervalFromDays maps a legacy day count onto the closest calendar cycle.
+// Counts that are not an obvious multiple of a week, month or year stay
+// day based so their renewal dates do not change.
+func intervalFromDays(days int) (int, models.BillingCycleUnit) {
+	switch {
+	case days <= 0:
+		return 1, models.BillingCycleUnitMonth
+	case days%365 == 0:
+		return days / 365, models.BillingCycleUnitYear
+	case days%30 == 0:
+		return days / 30, models.BillingCycleUnitMonth
+	case days%7 == 0:
+		return days / 7, models.BillingCycleUnitWeek
+	default:
+		return days, models.BillingCycleUnitDay
+	}
+}
+
+// migrateSubscriptionActiveFlag derives the lifecycle status of subscriptions
+// from the legacy active flag, then drops the active column.
+func migrateSubscriptionActiveFlag(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.Subscription{}, "active") {
+		return nil
+	}
+
+	log.Println("Converting subscription active flags to statuses...")
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			UPDATE subscriptions SET status = CASE
+				WHEN NOT active THEN ?
+				WHEN trial_end_date IS NOT NULL AND trial_converted_at IS NULL THEN ?
+				ELSE ?
+			END`,
+			models.SubscriptionStatusCancelled,
+			models.SubscriptionStatusTrialing,
+			models.SubscriptionStatusActive,
+		).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Migrator().DropColumn(&models.Subscription{}, "active")
+	})
+}
+
+// backfillBillingAnchorDays stores the renewal day of subscriptions created
+// before anchors were kept, taken from their next billing date, so month-end
+// dates clamped later return to the original day.
+func backfillBillingAnchorDays(db *gorm.DB) error {
+	return db.Exec(`
+		UPDATE subscriptions SET billing_anchor_day = EXTRACT(DAY FROM next_billing_date)
+		WHERE billing_anchor_day = 0`).Error
+}
+
+// backfillPriceHistory records the current price of subscriptions created
+// before price history was kept, effective from their creation, so a later
+// price edit does not lose the original price.
+func backfillPriceHistory(db *gorm.DB) error {
+	var subscriptions []models.Subscription
+	return db.Unscoped().
+		Where("NOT EXISTS (SELECT 1 FROM price_changes WHERE price_changes.subscription_id = subscriptions.id)").
+		FindInBatches(&subscriptions, 500, func(_ *gorm.DB, _ int) error {
+			priceChanges := make([]models.PriceChange, 0, len(subscriptions))
+			for _, subscription := range subscriptions {
+				appliedAt := subscription.CreatedAt
+				priceChanges = append(priceChanges, models.PriceChange{
+					UserID:         subscription.UserID,
+					SubscriptionID: subscription.ID,
+					Amount:         subscription.Amount,
+					CurrencyID:     subscription.CurrencyID,
+					EffectiveDate:  subscription.CreatedAt,
+					AppliedAt:      &appliedAt,
+				})
+			}
+			log.Printf("Recording the current price of %d subscriptions without price history...", len(priceChanges))
+			return db.Create(&priceChanges).Error
+		}).Error
+}
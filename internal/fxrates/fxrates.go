@@ -0,0 +1,162 @@
+// Package fxrates parses exchange-rate files for offline import.
+package fxrates
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format identifies the layout of a rates file.
+type Format string
+
+const (
+	// FormatECB is the European Central Bank eurofxref XML feed, daily or
+	// historical. All rates are quoted against the euro.
+	FormatECB Format = "ecb"
+	// FormatCSV is a file with a "date,base,quote,rate" header followed by
+	// one rate per line, e.g. "2024-05-02,EUR,USD,1.0735".
+	FormatCSV Format = "csv"
+)
+
+// ECBBaseCurrency is the currency every ECB reference rate is quoted against.
+const ECBBaseCurrency = "EUR"
+
+// Rate is the price of one unit of Base in Quote on Date.
+type Rate struct {
+	Base  string
+	Quote string
+	Rate  float64
+	Date  time.Time
+}
+
+func IsValidFormat(format Format) bool {
+	return format == FormatECB || format == FormatCSV
+}
+
+// DetectFormat guesses the format of a file from its name.
+func DetectFormat(filename string) Format {
+	if strings.EqualFold(filepath.Ext(filename), ".xml") {
+		return FormatECB
+	}
+	return FormatCSV
+}
+
+// Parse reads rates in the given format.
+func Parse(format Format, r io.Reader) ([]Rate, error) {
+	switch format {
+	case FormatECB:
+		return ParseECB(r)
+	case FormatCSV:
+		return ParseCSV(r)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECB reads the ECB eurofxref XML format.
+func ParseECB(r io.Reader) ([]Rate, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("parse ECB XML: %w", err)
+	}
+
+	var rates []Rate
+	for _, day := range envelope.Days {
+		date, err := time.Parse(time.DateOnly, day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", day.Time)
+		}
+		for _, entry := range day.Rates {
+			rate, err := parseRate(entry.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", day.Time, entry.Currency, err)
+			}
+			rates = append(rates, Rate{
+				Base:  ECBBaseCurrency,
+				Quote: strings.ToUpper(entry.Currency),
+				Rate:  rate,
+				Date:  date,
+			})
+		}
+	}
+
+	if len(rates) == 0 {
+		return nil, errors.New("no rates found in ECB XML")
+	}
+	return rates, nil
+}
+
+var csvHeader = []string{"date", "base", "quote", "rate"}
+
+// ParseCSV reads the simple CSV format described at FormatCSV.
+func ParseCSV(r io.Reader) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read CSV header: %w", err)
+	}
+	for i, column := range csvHeader {
+		if !strings.EqualFold(strings.TrimSpace(header[i]), column) {
+			return nil, fmt.Errorf("CSV header must be %q", strings.Join(csvHeader, ","))
+		}
+	}
+
+	var rates []Rate
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		date, err := time.Parse(time.DateOnly, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", line, record[0])
+		}
+		rate, err := parseRate(record[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, Rate{
+			Base:  strings.ToUpper(record[1]),
+			Quote: strings.ToUpper(record[2]),
+			Rate:  rate,
+			Date:  date,
+		})
+	}
+
+	if len(rates) == 0 {
+		return nil, errors.New("no rates found in CSV")
+	}
+	return rates, nil
+}
+
+func parseRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || rate <= 0 {
+		return 0, fmt.Errorf("invalid rate %q", value)
+	}
+	return rate, nil
+}
@@ -0,0 +1,184 @@
+package fxrates
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(value string) time.Time {
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		panic(err)
+	}
+	return date
+}
+
+func TestParseECB(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Rate
+		wantErr string
+	}{
+		{
+			name: "daily feed",
+			input: `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2024-05-02">
+			<Cube currency="USD" rate="1.0735"/>
+			<Cube currency="JPY" rate="165.94"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`,
+			want: []Rate{
+				{Base: "EUR", Quote: "USD", Rate: 1.0735, Date: day("2024-05-02")},
+				{Base: "EUR", Quote: "JPY", Rate: 165.94, Date: day("2024-05-02")},
+			},
+		},
+		{
+			name: "historical feed with lowercase currency",
+			input: `<Envelope><Cube>
+				<Cube time="2024-05-02"><Cube currency="usd" rate="1.0735"/></Cube>
+				<Cube time="2024-05-01"><Cube currency="USD" rate=" 1.0702 "/></Cube>
+			</Cube></Envelope>`,
+			want: []Rate{
+				{Base: "EUR", Quote: "USD", Rate: 1.0735, Date: day("2024-05-02")},
+				{Base: "EUR", Quote: "USD", Rate: 1.0702, Date: day("2024-05-01")},
+			},
+		},
+		{
+			name:    "invalid date",
+			input:   `<Envelope><Cube><Cube time="02.05.2024"><Cube currency="USD" rate="1.07"/></Cube></Cube></Envelope>`,
+			wantErr: `invalid date "02.05.2024"`,
+		},
+		{
+			name:    "invalid rate",
+			input:   `<Envelope><Cube><Cube time="2024-05-02"><Cube currency="USD" rate="n/a"/></Cube></Cube></Envelope>`,
+			wantErr: `2024-05-02 USD: invalid rate "n/a"`,
+		},
+		{
+			name:    "negative rate",
+			input:   `<Envelope><Cube><Cube time="2024-05-02"><Cube currency="USD" rate="-1"/></Cube></Cube></Envelope>`,
+			wantErr: `invalid rate "-1"`,
+		},
+		{
+			name:    "no rates",
+			input:   `<Envelope><Cube></Cube></Envelope>`,
+			wantErr: "no rates found in ECB XML",
+		},
+		{
+			name:    "not XML",
+			input:   `date,base,quote,rate`,
+			wantErr: "parse ECB XML",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseECB(strings.NewReader(tt.input))
+			checkRates(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Rate
+		wantErr string
+	}{
+		{
+			name:  "rates",
+			input: "date,base,quote,rate\n2024-05-02,EUR,USD,1.0735\n2024-05-02,usd,sgd,1.3512\n",
+			want: []Rate{
+				{Base: "EUR", Quote: "USD", Rate: 1.0735, Date: day("2024-05-02")},
+				{Base: "USD", Quote: "SGD", Rate: 1.3512, Date: day("2024-05-02")},
+			},
+		},
+		{
+			name:  "header in any case with spaces",
+			input: "Date, Base, Quote, Rate\n2024-05-02, EUR, GBP, 0.8537\n",
+			want: []Rate{
+				{Base: "EUR", Quote: "GBP", Rate: 0.8537, Date: day("2024-05-02")},
+			},
+		},
+		{
+			name:    "wrong header",
+			input:   "day,from,to,value\n2024-05-02,EUR,USD,1.07\n",
+			wantErr: `CSV header must be "date,base,quote,rate"`,
+		},
+		{
+			name:    "invalid date",
+			input:   "date,base,quote,rate\n2024-05-02,EUR,USD,1.07\n05/02/2024,EUR,USD,1.07\n",
+			wantErr: `line 3: invalid date "05/02/2024"`,
+		},
+		{
+			name:    "invalid rate",
+			input:   "date,base,quote,rate\n2024-05-02,EUR,USD,0\n",
+			wantErr: `line 2: invalid rate "0"`,
+		},
+		{
+			name:    "wrong number of fields",
+			input:   "date,base,quote,rate\n2024-05-02,EUR,USD\n",
+			wantErr: "read CSV",
+		},
+		{
+			name:    "header only",
+			input:   "date,base,quote,rate\n",
+			wantErr: "no rates found in CSV",
+		},
+		{
+			name:    "empty",
+			input:   "",
+			wantErr: "read CSV header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSV(strings.NewReader(tt.input))
+			checkRates(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestParseUnsupportedFormat(t *testing.T) {
+	if _, err := Parse("json", strings.NewReader("{}")); err == nil {
+		t.Fatal("Parse() with an unsupported format returned no error")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]Format{
+		"eurofxref-hist.xml": FormatECB,
+		"EUROFXREF.XML":      FormatECB,
+		"rates.csv":          FormatCSV,
+		"rates":              FormatCSV,
+	}
+	for filename, want := range tests {
+		if got := DetectFormat(filename); got != want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", filename, got, want)
+		}
+	}
+}
+
+func checkRates(t *testing.T, got []Rate, err error, want []Rate, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("error = %v, want it to contain %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rates = %+v, want %+v", got, want)
+	}
+}
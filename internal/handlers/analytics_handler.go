@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnalyticsHandler struct {
+	analyticsService *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(analyticsService *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsService: analyticsService,
+	}
+}
+
+func (h *AnalyticsHandler) Spend(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	report, err := h.analyticsService.Spend(userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(report))
+}
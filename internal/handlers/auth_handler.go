@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"subscription-tracker/internal/models"
 	"subscription-tracker/internal/services"
 	"subscription-tracker/internal/utils"
 
@@ -50,3 +51,53 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, utils.SuccessResponse(response))
 }
+
+// Refresh handles POST /auth/refresh. The refresh token in the request is
+// replaced by the one in the response.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req services.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("body", "invalid request body"))
+		return
+	}
+
+	response, err := h.authService.Refresh(&req)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(response))
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req services.LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("body", "invalid request body"))
+		return
+	}
+
+	if err := h.authService.Logout(&req); err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(nil))
+}
+
+// LogoutAll handles POST /me/logout-all, signing the user out of every
+// session including the one making the request.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	if err := h.authService.LogoutAll(userID.(models.ULID)); err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(nil))
+}
@@ -85,6 +85,7 @@ func (h *BillingCycleHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.SuccessResponse(billingCycle))
 }
 
+// Delete handles DELETE /billing-cycles/:id?reassignTo=:targetId.
 func (h *BillingCycleHandler) Delete(c *gin.Context) {
 	var billingCycleID models.ULID
 	if err := billingCycleID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
@@ -98,16 +99,14 @@ func (h *BillingCycleHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	err := h.billingCycleService.Delete(billingCycleID, userID.(models.ULID))
+	reassignTo, err := parseOptionalID(c, "reassignTo")
 	if err != nil {
-		switch err.Error() {
-		case "billing cycle not found":
-			c.JSON(http.StatusNotFound, utils.ErrorResponse(err.Error()))
-		case "cannot delete system-defined billing cycle":
-			c.JSON(http.StatusForbidden, utils.ErrorResponse(err.Error()))
-		default:
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
-		}
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	if err := h.billingCycleService.Delete(billingCycleID, userID.(models.ULID), reassignTo); err != nil {
+		utils.HandleHttpError(c, err)
 		return
 	}
 
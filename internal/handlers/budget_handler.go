@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BudgetHandler struct {
+	budgetService *services.BudgetService
+}
+
+func NewBudgetHandler(budgetService *services.BudgetService) *BudgetHandler {
+	return &BudgetHandler{
+		budgetService: budgetService,
+	}
+}
+
+func (h *BudgetHandler) Create(c *gin.Context) {
+	var req services.CreateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("body", "invalid request body"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	budget, err := h.budgetService.Create(&req, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse(budget))
+}
+
+func (h *BudgetHandler) GetAll(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	budgets, err := h.budgetService.GetAll(userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(budgets))
+}
+
+func (h *BudgetHandler) GetByID(c *gin.Context) {
+	var budgetID models.ULID
+	if err := budgetID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid budget ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	budget, err := h.budgetService.GetByID(budgetID, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(budget))
+}
+
+func (h *BudgetHandler) Update(c *gin.Context) {
+	var budgetID models.ULID
+	if err := budgetID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid budget ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	var req services.UpdateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("body", "invalid request body"))
+		return
+	}
+
+	budget, err := h.budgetService.Update(budgetID, &req, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(budget))
+}
+
+func (h *BudgetHandler) Delete(c *gin.Context) {
+	var budgetID models.ULID
+	if err := budgetID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid budget ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	if err := h.budgetService.Delete(budgetID, userID.(models.ULID)); err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(nil))
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarHandler struct {
+	calendarService *services.CalendarService
+}
+
+func NewCalendarHandler(calendarService *services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: calendarService,
+	}
+}
+
+func (h *CalendarHandler) CreateToken(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	response, err := h.calendarService.CreateToken(userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	response.URL = feedURL(c, response.Token)
+	c.JSON(http.StatusCreated, utils.SuccessResponse(response))
+}
+
+func (h *CalendarHandler) RevokeToken(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	if err := h.calendarService.RevokeToken(userID.(models.ULID)); err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(nil))
+}
+
+// Feed serves the iCalendar feed. It is authenticated by the secret token in
+// the URL rather than by AuthMiddleware.
+func (h *CalendarHandler) Feed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	feed, err := h.calendarService.Feed(token)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", feed)
+}
+
+func feedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/api/v1/calendar/feed/" + token + ".ics"
+}
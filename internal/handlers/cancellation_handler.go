@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CancellationHandler struct {
+	cancellationService *services.CancellationService
+}
+
+func NewCancellationHandler(cancellationService *services.CancellationService) *CancellationHandler {
+	return &CancellationHandler{
+		cancellationService: cancellationService,
+	}
+}
+
+// Cancel handles POST /subscriptions/:id/cancel. The request body is
+// optional.
+func (h *CancellationHandler) Cancel(c *gin.Context) {
+	var subscriptionID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	var req services.CancelSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.HandleHttpError(c, utils.NewValidationError("body", "invalid request body"))
+		return
+	}
+
+	cancellation, err := h.cancellationService.Cancel(subscriptionID, &req, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse(cancellation))
+}
+
+// Savings handles GET /subscriptions/:id/cancellation.
+func (h *CancellationHandler) Savings(c *gin.Context) {
+	var subscriptionID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	savings, err := h.cancellationService.Savings(subscriptionID, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(savings))
+}
+
+// AllSavings handles GET /subscriptions/savings.
+func (h *CancellationHandler) AllSavings(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	savings, err := h.cancellationService.AllSavings(userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(savings))
+}
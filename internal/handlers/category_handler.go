@@ -97,6 +97,7 @@ func (h *CategoryHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.SuccessResponse(category))
 }
 
+// Delete handles DELETE /categories/:id?reassignTo=:targetId.
 func (h *CategoryHandler) Delete(c *gin.Context) {
 	var categoryID models.ULID
 	if err := categoryID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
@@ -110,7 +111,13 @@ func (h *CategoryHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.categoryService.Delete(categoryID, userID.(models.ULID)); err != nil {
+	reassignTo, err := parseOptionalID(c, "reassignTo")
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	if err := h.categoryService.Delete(categoryID, userID.(models.ULID), reassignTo); err != nil {
 		utils.HandleHttpError(c, err)
 		return
 	}
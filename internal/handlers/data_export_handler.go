@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DataExportHandler struct {
+	dataExportService *services.DataExportService
+}
+
+func NewDataExportHandler(dataExportService *services.DataExportService) *DataExportHandler {
+	return &DataExportHandler{
+		dataExportService: dataExportService,
+	}
+}
+
+// Request handles POST /me/export. The archive is built in the background;
+// poll Status until it is ready.
+func (h *DataExportHandler) Request(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	export, created, err := h.dataExportService.Request(userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, utils.SuccessResponse(export))
+}
+
+func (h *DataExportHandler) Status(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	export, err := h.dataExportService.GetLatest(userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(export))
+}
+
+// Download serves the archive of the latest ready export.
+func (h *DataExportHandler) Download(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	export, err := h.dataExportService.Download(userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	filename := "subscription-tracker-export-" + export.CompletedAt.Format(time.DateOnly) + ".zip"
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", export.Archive)
+}
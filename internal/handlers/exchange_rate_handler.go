@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"subscription-tracker/internal/fxrates"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRatesFileSize bounds uploaded rate files; the full ECB history is
+// well below this.
+const maxRatesFileSize = 32 << 20
+
+type ExchangeRateHandler struct {
+	exchangeRateService *services.ExchangeRateService
+}
+
+func NewExchangeRateHandler(exchangeRateService *services.ExchangeRateService) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		exchangeRateService: exchangeRateService,
+	}
+}
+
+// GetLatest handles GET /exchange-rates?date=YYYY-MM-DD.
+func (h *ExchangeRateHandler) GetLatest(c *gin.Context) {
+	asOf := time.Now()
+	if value := c.Query("date"); value != "" {
+		t, dateOnly, err := parseDate(value)
+		if err != nil {
+			utils.HandleHttpError(c, utils.NewValidationError("date", "date must be a date (YYYY-MM-DD) or RFC 3339 timestamp"))
+			return
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		asOf = t
+	}
+
+	rates, err := h.exchangeRateService.GetLatest(asOf)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(rates))
+}
+
+// Set handles POST /admin/exchange-rates.
+func (h *ExchangeRateHandler) Set(c *gin.Context) {
+	var req services.SetExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("body", "invalid request body"))
+		return
+	}
+
+	rate, err := h.exchangeRateService.Set(&req)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse(rate))
+}
+
+// Import handles POST /admin/exchange-rates/import. The file is sent either
+// as the "file" field of a multipart form or as the raw request body.
+// format (ecb or csv) defaults to a guess from the file name and source
+// defaults to the file name.
+func (h *ExchangeRateHandler) Import(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	file, filename, err := openUpload(c, maxRatesFileSize)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+	defer file.Close()
+
+	format := fxrates.Format(strings.ToLower(c.Query("format")))
+	if format == "" {
+		if filename == "" {
+			utils.HandleHttpError(c, utils.NewValidationError("format", "format is required"))
+			return
+		}
+		format = fxrates.DetectFormat(filename)
+	}
+	source := c.DefaultQuery("source", filename)
+
+	importedBy := userID.(models.ULID)
+	rateImport, err := h.exchangeRateService.Import(format, source, file, &importedBy)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse(rateImport))
+}
+
+// GetImports handles GET /admin/exchange-rates/imports.
+func (h *ExchangeRateHandler) GetImports(c *gin.Context) {
+	imports, err := h.exchangeRateService.GetImports()
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(imports))
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ForecastHandler struct {
+	forecastService *services.ForecastService
+}
+
+func NewForecastHandler(forecastService *services.ForecastService) *ForecastHandler {
+	return &ForecastHandler{
+		forecastService: forecastService,
+	}
+}
+
+// Forecast handles GET /forecast?months=12.
+func (h *ForecastHandler) Forecast(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	months, err := strconv.Atoi(c.DefaultQuery("months", strconv.Itoa(services.DefaultForecastMonths)))
+	if err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("months", "months must be a number"))
+		return
+	}
+
+	forecast, err := h.forecastService.Forecast(userID.(models.ULID), months)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(forecast))
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseDateRange reads the from and to query parameters as RFC 3339
+// timestamps or YYYY-MM-DD dates. A bare to date includes the whole day.
+// from defaults to now and to defaults to defaultDays after from.
+func parseDateRange(c *gin.Context, defaultDays int) (time.Time, time.Time, error) {
+	from := time.Now()
+	if value := c.Query("from"); value != "" {
+		t, _, err := parseDate(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, utils.NewValidationError("from", "from must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+		}
+		from = t
+	}
+
+	to := from.AddDate(0, 0, defaultDays)
+	if value := c.Query("to"); value != "" {
+		t, dateOnly, err := parseDate(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, utils.NewValidationError("to", "to must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		to = t
+	}
+
+	return from, to, nil
+}
+
+func parseDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	return t, true, err
+}
+
+// parseOptionalID reads an optional ULID query parameter.
+func parseOptionalID(c *gin.Context, name string) (*models.ULID, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	var id models.ULID
+	if err := id.UnmarshalJSON([]byte(`"` + value + `"`)); err != nil {
+		return nil, utils.NewValidationError(name, "invalid "+name+" ID")
+	}
+	return &id, nil
+}
+
+// openUpload returns an uploaded file, sent either as the "file" field of a
+// multipart form or as the raw request body, and its base name. The name is
+// empty for raw bodies. The body is limited to maxSize bytes.
+func openUpload(c *gin.Context, maxSize int64) (io.ReadCloser, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	if !strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		return c.Request.Body, "", nil
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", utils.NewValidationError("file", "file is required")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, "", utils.NewValidationError("file", "file could not be read")
+	}
+	return file, filepath.Base(header.Filename), nil
+}
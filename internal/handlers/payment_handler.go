@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentHandler struct {
+	paymentService *services.PaymentService
+}
+
+func NewPaymentHandler(paymentService *services.PaymentService) *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: paymentService,
+	}
+}
+
+func (h *PaymentHandler) GetAll(c *gin.Context) {
+	var subscriptionID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	history, err := h.paymentService.GetAll(subscriptionID, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(history))
+}
+
+func (h *PaymentHandler) Create(c *gin.Context) {
+	var subscriptionID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+
+	var req services.CreatePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("body", "invalid request body"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	payment, err := h.paymentService.Create(subscriptionID, &req, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse(payment))
+}
+
+func (h *PaymentHandler) Update(c *gin.Context) {
+	var subscriptionID, paymentID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+	if err := paymentID.UnmarshalJSON([]byte(`"` + c.Param("paymentId") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("paymentId", "invalid payment ID"))
+		return
+	}
+
+	var req services.UpdatePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("body", "invalid request body"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	payment, err := h.paymentService.Update(subscriptionID, paymentID, &req, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(payment))
+}
+
+func (h *PaymentHandler) Delete(c *gin.Context) {
+	var subscriptionID, paymentID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+	if err := paymentID.UnmarshalJSON([]byte(`"` + c.Param("paymentId") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("paymentId", "invalid payment ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	if err := h.paymentService.Delete(subscriptionID, paymentID, userID.(models.ULID)); err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(nil))
+}
@@ -48,7 +48,7 @@ func (h *PaymentMethodHandler) GetAll(c *gin.Context) {
 		return
 	}
 
-	paymentMethods, err := h.paymentMethodService.GetAll(userID.(models.ULID))
+	paymentMethods, err := h.paymentMethodService.GetAll(userID.(models.ULID), c.Query("archived") == "true")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
 		return
@@ -57,6 +57,52 @@ func (h *PaymentMethodHandler) GetAll(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.SuccessResponse(paymentMethods))
 }
 
+// Migrate handles POST /payment-methods/:id/migrate.
+func (h *PaymentMethodHandler) Migrate(c *gin.Context) {
+	var paymentMethodID models.ULID
+	if err := paymentMethodID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid payment method ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	var req services.MigratePaymentMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("body", "invalid request body"))
+		return
+	}
+
+	migration, err := h.paymentMethodService.Migrate(paymentMethodID, &req, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(migration))
+}
+
+// AtRisk handles GET /payment-methods/at-risk.
+func (h *PaymentMethodHandler) AtRisk(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	subscriptions, err := h.paymentMethodService.AtRisk(userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(subscriptions))
+}
+
 func (h *PaymentMethodHandler) Update(c *gin.Context) {
 	var paymentMethodID models.ULID
 	if err := paymentMethodID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
@@ -90,6 +136,7 @@ func (h *PaymentMethodHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.SuccessResponse(paymentMethod))
 }
 
+// Delete handles DELETE /payment-methods/:id?reassignTo=:targetId.
 func (h *PaymentMethodHandler) Delete(c *gin.Context) {
 	var paymentMethodID models.ULID
 	if err := paymentMethodID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
@@ -103,7 +150,13 @@ func (h *PaymentMethodHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.paymentMethodService.Delete(paymentMethodID, userID.(models.ULID)); err != nil {
+	reassignTo, err := parseOptionalID(c, "reassignTo")
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	if err := h.paymentMethodService.Delete(paymentMethodID, userID.(models.ULID), reassignTo); err != nil {
 		utils.HandleHttpError(c, err)
 		return
 	}
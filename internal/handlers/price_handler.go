@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PriceHandler struct {
+	priceService *services.PriceService
+}
+
+func NewPriceHandler(priceService *services.PriceService) *PriceHandler {
+	return &PriceHandler{
+		priceService: priceService,
+	}
+}
+
+func (h *PriceHandler) GetHistory(c *gin.Context) {
+	var subscriptionID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	priceChanges, err := h.priceService.GetHistory(subscriptionID, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(priceChanges))
+}
+
+func (h *PriceHandler) GetEffective(c *gin.Context) {
+	var subscriptionID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	date := time.Now()
+	if value := c.Query("date"); value != "" {
+		t, _, err := parseDate(value)
+		if err != nil {
+			utils.HandleHttpError(c, utils.NewValidationError("date", "date must be a date (YYYY-MM-DD) or RFC 3339 timestamp"))
+			return
+		}
+		date = t
+	}
+
+	price, err := h.priceService.EffectiveAt(subscriptionID, userID.(models.ULID), date)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(price))
+}
+
+func (h *PriceHandler) Schedule(c *gin.Context) {
+	var subscriptionID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+
+	var req services.SchedulePriceChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("body", "invalid request body"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	priceChange, err := h.priceService.Schedule(subscriptionID, &req, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse(priceChange))
+}
+
+func (h *PriceHandler) CancelScheduled(c *gin.Context) {
+	var subscriptionID, priceChangeID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+	if err := priceChangeID.UnmarshalJSON([]byte(`"` + c.Param("priceId") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("priceId", "invalid price change ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	if err := h.priceService.CancelScheduled(subscriptionID, priceChangeID, userID.(models.ULID)); err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(nil))
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/statements"
+	"subscription-tracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxStatementFileSize bounds uploaded statements; a few years of card
+// transactions fit comfortably.
+const maxStatementFileSize = 10 << 20
+
+type StatementHandler struct {
+	statementService *services.StatementService
+}
+
+func NewStatementHandler(statementService *services.StatementService) *StatementHandler {
+	return &StatementHandler{
+		statementService: statementService,
+	}
+}
+
+// Import handles POST /statements/import. The statement is sent as the
+// "file" field of a multipart form or as the raw body; options, including
+// the CSV column mapping, are query parameters.
+func (h *StatementHandler) Import(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	file, filename, err := openUpload(c, maxStatementFileSize)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+	defer file.Close()
+
+	format := statements.Format(strings.ToLower(c.Query("format")))
+	if format == "" {
+		if filename == "" {
+			utils.HandleHttpError(c, utils.NewValidationError("format", "format is required"))
+			return
+		}
+		format = statements.DetectFormat(filename)
+	}
+
+	req := &services.StatementImportRequest{
+		Format: format,
+		Mapping: statements.CSVMapping{
+			Date:           c.Query("dateColumn"),
+			Description:    c.Query("descriptionColumn"),
+			Amount:         c.Query("amountColumn"),
+			Debit:          c.Query("debitColumn"),
+			Credit:         c.Query("creditColumn"),
+			Currency:       c.Query("currencyColumn"),
+			DateFormat:     c.Query("dateFormat"),
+			DebitsPositive: c.Query("debitsPositive") == "true",
+			Delimiter:      c.Query("delimiter"),
+		},
+		PaymentMethodID: c.Query("paymentMethodId"),
+		CategoryID:      c.Query("categoryId"),
+		Currency:        c.Query("currency"),
+	}
+
+	result, err := h.statementService.Import(req, file, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse(result))
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"subscription-tracker/internal/models"
 	"subscription-tracker/internal/services"
 	"subscription-tracker/internal/utils"
@@ -32,13 +35,13 @@ func (h *SubscriptionHandler) Create(c *gin.Context) {
 		return
 	}
 
-	subscription, err := h.subscriptionService.Create(&req, userID.(models.ULID))
+	subscription, warnings, err := h.subscriptionService.Create(&req, userID.(models.ULID))
 	if err != nil {
 		utils.HandleHttpError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, utils.SuccessResponse(subscription))
+	c.JSON(http.StatusCreated, utils.SuccessWarningResponse(subscription, warnings))
 }
 
 func (h *SubscriptionHandler) GetAll(c *gin.Context) {
@@ -48,15 +51,44 @@ func (h *SubscriptionHandler) GetAll(c *gin.Context) {
 		return
 	}
 
-	subscriptions, err := h.subscriptionService.GetAll(userID.(models.ULID))
+	var statuses []models.SubscriptionStatus
+	if value := c.Query("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			statuses = append(statuses, models.SubscriptionStatus(strings.TrimSpace(status)))
+		}
+	}
+
+	subscriptions, err := h.subscriptionService.GetAll(userID.(models.ULID), statuses...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(err.Error()))
+		utils.HandleHttpError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(subscriptions))
 }
 
+func (h *SubscriptionHandler) Upcoming(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	from, to, err := parseDateRange(c, 30)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	renewals, err := h.subscriptionService.Upcoming(userID.(models.ULID), from, to)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(renewals))
+}
+
 func (h *SubscriptionHandler) GetByID(c *gin.Context) {
 	var subscriptionID models.ULID
 	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
@@ -168,7 +200,7 @@ func (h *SubscriptionHandler) Update(c *gin.Context) {
 		return
 	}
 
-	subscription, err := h.subscriptionService.Update(subscriptionID, &req, userID.(models.ULID))
+	subscription, warnings, err := h.subscriptionService.Update(subscriptionID, &req, userID.(models.ULID))
 	if err != nil {
 		switch err.Error() {
 		case "subscription not found":
@@ -179,9 +211,134 @@ func (h *SubscriptionHandler) Update(c *gin.Context) {
 		return
 	}
 
+	c.JSON(http.StatusOK, utils.SuccessWarningResponse(subscription, warnings))
+}
+
+// Confirm handles POST /subscriptions/:id/confirm.
+func (h *SubscriptionHandler) Confirm(c *gin.Context) {
+	var subscriptionID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	subscription, warnings, err := h.subscriptionService.Confirm(subscriptionID, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessWarningResponse(subscription, warnings))
+}
+
+// Pause handles POST /subscriptions/:id/pause. The request body is
+// optional.
+func (h *SubscriptionHandler) Pause(c *gin.Context) {
+	var subscriptionID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	var req services.PauseSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.HandleHttpError(c, utils.NewValidationError("body", "invalid request body"))
+		return
+	}
+
+	pauseWindow, err := h.subscriptionService.Pause(subscriptionID, &req, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse(pauseWindow))
+}
+
+// Resume handles POST /subscriptions/:id/resume.
+func (h *SubscriptionHandler) Resume(c *gin.Context) {
+	var subscriptionID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	subscription, err := h.subscriptionService.Resume(subscriptionID, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, utils.SuccessResponse(subscription))
 }
 
+// GetPauses handles GET /subscriptions/:id/pauses.
+func (h *SubscriptionHandler) GetPauses(c *gin.Context) {
+	var subscriptionID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	pauseWindows, err := h.subscriptionService.GetPauses(subscriptionID, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(pauseWindows))
+}
+
+// CancelPause handles DELETE /subscriptions/:id/pauses/:pauseId.
+func (h *SubscriptionHandler) CancelPause(c *gin.Context) {
+	var subscriptionID, pauseID models.ULID
+	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("id", "invalid subscription ID"))
+		return
+	}
+	if err := pauseID.UnmarshalJSON([]byte(`"` + c.Param("pauseId") + `"`)); err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("pauseId", "invalid pause ID"))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	if err := h.subscriptionService.CancelPause(subscriptionID, pauseID, userID.(models.ULID)); err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(nil))
+}
+
 func (h *SubscriptionHandler) Delete(c *gin.Context) {
 	var subscriptionID models.ULID
 	if err := subscriptionID.UnmarshalJSON([]byte(`"` + c.Param("id") + `"`)); err != nil {
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSubscriptionFileSize bounds uploaded subscription files, well above
+// maxImportRows rows.
+const maxSubscriptionFileSize = 5 << 20
+
+type SubscriptionTransferHandler struct {
+	transferService *services.SubscriptionTransferService
+}
+
+func NewSubscriptionTransferHandler(transferService *services.SubscriptionTransferService) *SubscriptionTransferHandler {
+	return &SubscriptionTransferHandler{
+		transferService: transferService,
+	}
+}
+
+// Export handles GET /subscriptions/export?format=csv|json and serves the
+// file as an attachment. The format defaults to csv.
+func (h *SubscriptionTransferHandler) Export(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	format := services.TransferFormat(strings.ToLower(c.DefaultQuery("format", string(services.TransferFormatCSV))))
+	data, err := h.transferService.Export(format, userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == services.TransferFormatJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	filename := fmt.Sprintf("subscriptions-%s.%s", time.Now().Format(time.DateOnly), format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// Import handles POST /subscriptions/import. The file is sent as the "file"
+// field of a multipart form or as the raw body. The format is taken from
+// the format query parameter or the file extension; createMissing=true
+// creates categories, billing cycles and payment methods that do not exist.
+func (h *SubscriptionTransferHandler) Import(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	file, filename, err := openUpload(c, maxSubscriptionFileSize)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+	defer file.Close()
+
+	format := services.TransferFormat(strings.ToLower(c.Query("format")))
+	if format == "" {
+		if filename == "" {
+			utils.HandleHttpError(c, utils.NewValidationError("format", "format is required"))
+			return
+		}
+		format = services.TransferFormat(strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), ".")))
+	}
+
+	result, err := h.transferService.Import(format, file, c.Query("createMissing") == "true", userID.(models.ULID))
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
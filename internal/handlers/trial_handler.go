@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/services"
+	"subscription-tracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrialHandler struct {
+	trialService *services.TrialService
+}
+
+func NewTrialHandler(trialService *services.TrialService) *TrialHandler {
+	return &TrialHandler{
+		trialService: trialService,
+	}
+}
+
+func (h *TrialHandler) EndingSoon(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil {
+		utils.HandleHttpError(c, utils.NewValidationError("days", "days must be a number"))
+		return
+	}
+
+	subscriptions, err := h.trialService.EndingSoon(userID.(models.ULID), days)
+	if err != nil {
+		utils.HandleHttpError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(subscriptions))
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware only lets administrators through. It must run after
+// AuthMiddleware.
+func AdminMiddleware(userRepo *repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found in context"))
+			c.Abort()
+			return
+		}
+
+		user, err := userRepo.GetByID(userID.(models.ULID))
+		if err != nil {
+			utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found"))
+			c.Abort()
+			return
+		}
+		if !user.IsAdmin {
+			utils.HandleHttpError(c, utils.NewForbiddenError("administrator access required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
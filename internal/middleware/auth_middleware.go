@@ -6,12 +6,16 @@ import (
 
 	"subscription-tracker/internal/auth"
 	"subscription-tracker/internal/config"
+	"subscription-tracker/internal/repository"
 	"subscription-tracker/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// AuthMiddleware accepts valid bearer tokens of existing users that were
+// issued after the user last revoked their tokens. Accounts awaiting
+// deletion are locked out until they are restored by logging in.
+func AuthMiddleware(cfg *config.Config, userRepo *repository.UserRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -29,7 +33,24 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 
 		claims, err := auth.ValidateToken(parts[1], cfg)
 		if err != nil {
-			utils.HandleHttpError(c, err)
+			utils.HandleHttpError(c, utils.NewUnauthorizedError(err.Error()))
+			c.Abort()
+			return
+		}
+
+		user, err := userRepo.GetByID(claims.UserID)
+		if err != nil {
+			utils.HandleHttpError(c, utils.NewUnauthorizedError("user not found"))
+			c.Abort()
+			return
+		}
+		if claims.TokenVersion != user.TokenVersion {
+			utils.HandleHttpError(c, utils.NewUnauthorizedError("token has been revoked"))
+			c.Abort()
+			return
+		}
+		if user.DeletesAt != nil {
+			utils.HandleHttpError(c, utils.NewUnauthorizedError("account is scheduled for deletion"))
 			c.Abort()
 			return
 		}
@@ -6,13 +6,34 @@ import (
 	"gorm.io/gorm"
 )
 
+type BillingCycleUnit string
+
+const (
+	BillingCycleUnitDay   BillingCycleUnit = "day"
+	BillingCycleUnitWeek  BillingCycleUnit = "week"
+	BillingCycleUnitMonth BillingCycleUnit = "month"
+	BillingCycleUnitYear  BillingCycleUnit = "year"
+)
+
+func IsValidBillingCycleUnit(unit BillingCycleUnit) bool {
+	switch unit {
+	case BillingCycleUnitDay,
+		BillingCycleUnitWeek,
+		BillingCycleUnitMonth,
+		BillingCycleUnitYear:
+		return true
+	}
+	return false
+}
+
 type BillingCycle struct {
-	ID            ULID   `gorm:"primaryKey;type:char(26)"`
-	Name          string `gorm:"not null"`
-	Days          int    `gorm:"not null"`
-	SystemDefined bool   `gorm:"not null;default:false"`
-	UserID        *ULID  `gorm:"type:char(26);index"`
-	User          *User  `gorm:"foreignKey:UserID"`
+	ID            ULID             `gorm:"primaryKey;type:char(26)"`
+	Name          string           `gorm:"not null"`
+	Interval      int              `gorm:"column:interval_count;not null;default:1"`
+	Unit          BillingCycleUnit `gorm:"not null;type:varchar(10);default:'month'"`
+	SystemDefined bool             `gorm:"not null;default:false"`
+	UserID        *ULID            `gorm:"type:char(26);index"`
+	User          *User            `gorm:"foreignKey:UserID"`
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	DeletedAt     gorm.DeletedAt `gorm:"index"`
@@ -20,14 +41,80 @@ type BillingCycle struct {
 
 // Default billing cycles
 var DefaultBillingCycles = []BillingCycle{
-	{Name: "Weekly", Days: 7, SystemDefined: true},
-	{Name: "Monthly", Days: 30, SystemDefined: true},
-	{Name: "Quarterly", Days: 90, SystemDefined: true},
-	{Name: "Semi-Annual", Days: 180, SystemDefined: true},
-	{Name: "Yearly", Days: 365, SystemDefined: true},
+	{Name: "Weekly", Interval: 1, Unit: BillingCycleUnitWeek, SystemDefined: true},
+	{Name: "Monthly", Interval: 1, Unit: BillingCycleUnitMonth, SystemDefined: true},
+	{Name: "Quarterly", Interval: 3, Unit: BillingCycleUnitMonth, SystemDefined: true},
+	{Name: "Semi-Annual", Interval: 6, Unit: BillingCycleUnitMonth, SystemDefined: true},
+	{Name: "Yearly", Interval: 1, Unit: BillingCycleUnitYear, SystemDefined: true},
 }
 
-// CalculateNextBillingDate calculates the next billing date based on the cycle days
+// CalculateNextBillingDate calculates the billing date one cycle after from,
+// anchored on the day of month of from.
 func (bc *BillingCycle) CalculateNextBillingDate(from time.Time) time.Time {
-	return from.AddDate(0, 0, bc.Days)
+	return bc.NextDate(from, from.Day())
+}
+
+// NextDate returns the billing date one cycle after from. For month and year
+// based cycles the result falls on anchorDay, clamped to the last day of the
+// month when the month is shorter (Jan 31 -> Feb 28 -> Mar 31).
+func (bc *BillingCycle) NextDate(from time.Time, anchorDay int) time.Time {
+	interval := bc.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch bc.Unit {
+	case BillingCycleUnitDay:
+		return from.AddDate(0, 0, interval)
+	case BillingCycleUnitWeek:
+		return from.AddDate(0, 0, 7*interval)
+	case BillingCycleUnitYear:
+		return addMonthsClamped(from, 12*interval, anchorDay)
+	default:
+		return addMonthsClamped(from, interval, anchorDay)
+	}
+}
+
+// MonthlyFactor converts one billing period into months, e.g. 1/12 for a
+// yearly cycle. It matches the normalization used by the spend analytics.
+func (bc *BillingCycle) MonthlyFactor() float64 {
+	interval := bc.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	var perMonth float64
+	switch bc.Unit {
+	case BillingCycleUnitDay:
+		perMonth = 365.0 / 12
+	case BillingCycleUnitWeek:
+		perMonth = 365.0 / 7 / 12
+	case BillingCycleUnitYear:
+		perMonth = 1.0 / 12
+	default:
+		perMonth = 1
+	}
+	return perMonth / float64(interval)
+}
+
+// addMonthsClamped moves t forward by months, placing it on anchorDay or on
+// the last day of the target month if anchorDay does not exist there.
+func addMonthsClamped(t time.Time, months, anchorDay int) time.Time {
+	if anchorDay < 1 {
+		anchorDay = t.Day()
+	}
+
+	year, month, _ := t.Date()
+	firstOfTarget := time.Date(year, month+time.Month(months), 1, 0, 0, 0, 0, t.Location())
+	day := anchorDay
+	if last := daysInMonth(firstOfTarget); day > last {
+		day = last
+	}
+
+	hour, min, sec := t.Clock()
+	return time.Date(firstOfTarget.Year(), firstOfTarget.Month(), day, hour, min, sec, t.Nanosecond(), t.Location())
+}
+
+func daysInMonth(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
 }
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestBillingCycleNextDate(t *testing.T) {
+	tests := []struct {
+		name      string
+		cycle     BillingCycle
+		from      time.Time
+		anchorDay int
+		want      time.Time
+	}{
+		{"daily", BillingCycle{Interval: 1, Unit: BillingCycleUnitDay}, date(2024, 2, 28), 28, date(2024, 2, 29)},
+		{"every 10 days across months", BillingCycle{Interval: 10, Unit: BillingCycleUnitDay}, date(2024, 1, 25), 25, date(2024, 2, 4)},
+		{"weekly", BillingCycle{Interval: 1, Unit: BillingCycleUnitWeek}, date(2024, 12, 28), 28, date(2025, 1, 4)},
+		{"biweekly", BillingCycle{Interval: 2, Unit: BillingCycleUnitWeek}, date(2024, 3, 1), 1, date(2024, 3, 15)},
+		{"monthly", BillingCycle{Interval: 1, Unit: BillingCycleUnitMonth}, date(2024, 3, 15), 15, date(2024, 4, 15)},
+		{"monthly clamped to leap February", BillingCycle{Interval: 1, Unit: BillingCycleUnitMonth}, date(2024, 1, 31), 31, date(2024, 2, 29)},
+		{"monthly clamped to February", BillingCycle{Interval: 1, Unit: BillingCycleUnitMonth}, date(2023, 1, 31), 31, date(2023, 2, 28)},
+		{"monthly returns to anchor after clamping", BillingCycle{Interval: 1, Unit: BillingCycleUnitMonth}, date(2023, 2, 28), 31, date(2023, 3, 31)},
+		{"monthly clamped to 30 day month", BillingCycle{Interval: 1, Unit: BillingCycleUnitMonth}, date(2024, 3, 31), 31, date(2024, 4, 30)},
+		{"monthly across year end", BillingCycle{Interval: 1, Unit: BillingCycleUnitMonth}, date(2024, 12, 31), 31, date(2025, 1, 31)},
+		{"quarterly clamped", BillingCycle{Interval: 3, Unit: BillingCycleUnitMonth}, date(2024, 11, 30), 30, date(2025, 2, 28)},
+		{"zero interval treated as one", BillingCycle{Interval: 0, Unit: BillingCycleUnitMonth}, date(2024, 5, 10), 10, date(2024, 6, 10)},
+		{"missing anchor uses day of from", BillingCycle{Interval: 1, Unit: BillingCycleUnitMonth}, date(2024, 5, 10), 0, date(2024, 6, 10)},
+		{"yearly from leap day", BillingCycle{Interval: 1, Unit: BillingCycleUnitYear}, date(2024, 2, 29), 29, date(2025, 2, 28)},
+		{"yearly back to leap day", BillingCycle{Interval: 4, Unit: BillingCycleUnitYear}, date(2024, 2, 29), 29, date(2028, 2, 29)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cycle.NextDate(tt.from, tt.anchorDay); !got.Equal(tt.want) {
+				t.Errorf("NextDate(%s, %d) = %s, want %s", tt.from.Format(time.DateOnly), tt.anchorDay, got.Format(time.DateOnly), tt.want.Format(time.DateOnly))
+			}
+		})
+	}
+}
+
+func TestBillingCycleNextDateKeepsClock(t *testing.T) {
+	cycle := BillingCycle{Interval: 1, Unit: BillingCycleUnitMonth}
+	from := time.Date(2024, 1, 31, 9, 30, 0, 0, time.UTC)
+	want := time.Date(2024, 2, 29, 9, 30, 0, 0, time.UTC)
+	if got := cycle.NextDate(from, 31); !got.Equal(want) {
+		t.Errorf("NextDate = %s, want %s", got, want)
+	}
+}
+
+func TestBillingCycleMonthlyFactor(t *testing.T) {
+	tests := []struct {
+		cycle BillingCycle
+		want  float64
+	}{
+		{BillingCycle{Interval: 1, Unit: BillingCycleUnitMonth}, 1},
+		{BillingCycle{Interval: 3, Unit: BillingCycleUnitMonth}, 1.0 / 3},
+		{BillingCycle{Interval: 1, Unit: BillingCycleUnitYear}, 1.0 / 12},
+		{BillingCycle{Interval: 1, Unit: BillingCycleUnitWeek}, 365.0 / 7 / 12},
+		{BillingCycle{Interval: 1, Unit: BillingCycleUnitDay}, 365.0 / 12},
+	}
+
+	for _, tt := range tests {
+		if got := tt.cycle.MonthlyFactor(); got != tt.want {
+			t.Errorf("MonthlyFactor() for %d %s = %v, want %v", tt.cycle.Interval, tt.cycle.Unit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Budget caps the monthly spend of one category for a user, e.g.
+// "Streaming ≤ 40 USD/month". Category may be system-defined or owned by the
+// user; each user has at most one budget per category.
+type Budget struct {
+	ID         ULID     `gorm:"primaryKey;type:char(26)"`
+	UserID     ULID     `gorm:"type:char(26);not null;uniqueIndex:idx_budget_category,where:deleted_at IS NULL"`
+	CategoryID ULID     `gorm:"type:char(26);not null;uniqueIndex:idx_budget_category,where:deleted_at IS NULL"`
+	Category   Category `gorm:"foreignKey:CategoryID"`
+	Amount     float64  `gorm:"type:decimal(10,2);not null"` // Per month
+	CurrencyID ULID     `gorm:"type:char(26);not null"`
+	Currency   Currency `gorm:"foreignKey:CurrencyID"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+}
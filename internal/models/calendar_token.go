@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// CalendarToken grants read access to a user's iCalendar feed. Calendar apps
+// can't send Bearer tokens, so the secret is part of the feed URL instead.
+type CalendarToken struct {
+	ID         ULID   `gorm:"primaryKey;type:char(26)"`
+	UserID     ULID   `gorm:"type:char(26);not null;index"`
+	User       User   `gorm:"foreignKey:UserID" json:"-"`
+	TokenHash  string `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Cancellation records that a subscription was cancelled. The subscription
+// stays usable until EffectiveDate, usually the end of the paid period.
+// Amount and CurrencyID are the price that would have been charged on each
+// renewal, used to report the money saved since cancelling. A cancellation
+// is withdrawn when the subscription is resumed before it takes effect.
+type Cancellation struct {
+	ID                 ULID      `gorm:"primaryKey;type:char(26)"`
+	UserID             ULID      `gorm:"type:char(26);not null;index"`
+	SubscriptionID     ULID      `gorm:"type:char(26);not null;index"`
+	CancelledAt        time.Time `gorm:"not null"`
+	EffectiveDate      time.Time `gorm:"not null"`
+	Reason             string
+	ConfirmationNumber string
+	Amount             float64  `gorm:"type:decimal(10,2);not null"`
+	CurrencyID         ULID     `gorm:"type:char(26);not null"`
+	Currency           Currency `gorm:"foreignKey:CurrencyID"`
+	WithdrawnAt        *time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	DeletedAt          gorm.DeletedAt `gorm:"index"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type DataExportStatus string
+
+const (
+	DataExportStatusPending DataExportStatus = "pending"
+	DataExportStatusReady   DataExportStatus = "ready"
+	DataExportStatusFailed  DataExportStatus = "failed"
+)
+
+// DataExport is a ZIP archive of everything stored about a user, built in
+// the background on request. The archive is kept in the database so the API
+// and worker processes need no shared storage, and is deleted when it
+// expires.
+type DataExport struct {
+	ID          ULID             `gorm:"primaryKey;type:char(26)"`
+	UserID      ULID             `gorm:"type:char(26);not null;index"`
+	Status      DataExportStatus `gorm:"type:varchar(20);not null;default:'pending';index"`
+	Archive     []byte           `gorm:"type:bytea" json:"-"`
+	Size        int64            // Archive size in bytes
+	Error       string           // Why building the archive failed
+	CompletedAt *time.Time
+	ExpiresAt   *time.Time `gorm:"index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
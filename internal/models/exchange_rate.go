@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ExchangeRate is the price of one unit of the base currency in the quote
+// currency on RateDate. There is at most one rate per pair and date. Source
+// and ImportID record where the rate last came from.
+type ExchangeRate struct {
+	ID              ULID      `gorm:"primaryKey;type:char(26)"`
+	BaseCurrencyID  ULID      `gorm:"type:char(26);not null;uniqueIndex:idx_exchange_rate_pair_date"`
+	BaseCurrency    Currency  `gorm:"foreignKey:BaseCurrencyID"`
+	QuoteCurrencyID ULID      `gorm:"type:char(26);not null;uniqueIndex:idx_exchange_rate_pair_date"`
+	QuoteCurrency   Currency  `gorm:"foreignKey:QuoteCurrencyID"`
+	Rate            float64   `gorm:"type:decimal(20,10);not null"`
+	RateDate        time.Time `gorm:"type:date;not null;uniqueIndex:idx_exchange_rate_pair_date"`
+	Source          string    `gorm:"type:varchar(255)"`
+	ImportID        *ULID     `gorm:"type:char(26);index"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// ExchangeRateImport is the audit record of a rates file loaded through the
+// admin endpoint or the fximport command.
+type ExchangeRateImport struct {
+	ID                ULID      `gorm:"primaryKey;type:char(26)"`
+	Source            string    `gorm:"type:varchar(255);not null"`
+	Format            string    `gorm:"type:varchar(10);not null"`
+	ImportedByID      *ULID     `gorm:"type:char(26)"` // Nil when imported from the command line
+	RateCount         int       `gorm:"not null;default:0"`
+	SkippedCount      int       `gorm:"not null;default:0"`
+	UnknownCurrencies string    // Comma-separated codes that are not in the currencies table
+	ImportedAt        time.Time `gorm:"not null"`
+	CreatedAt         time.Time
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PauseWindow is a period during which a subscription is paused. A window
+// starting in the future stays scheduled until StartedAt is set. A nil
+// EndDate pauses until the subscription is resumed by hand. ResumedAt closes
+// the window; the subscription's next billing date is then pushed back by
+// the whole days spent paused.
+type PauseWindow struct {
+	ID             ULID       `gorm:"primaryKey;type:char(26)"`
+	UserID         ULID       `gorm:"type:char(26);not null;index"`
+	SubscriptionID ULID       `gorm:"type:char(26);not null;index"`
+	StartDate      time.Time  `gorm:"not null;index"`
+	EndDate        *time.Time `gorm:"index"`
+	StartedAt      *time.Time
+	ResumedAt      *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+}
+
+// IsScheduled reports whether the window has not started yet.
+func (p *PauseWindow) IsScheduled() bool {
+	return p.StartedAt == nil && p.ResumedAt == nil
+}
+
+// IsOpen reports whether the window has not been closed by a resume.
+func (p *PauseWindow) IsOpen() bool {
+	return p.ResumedAt == nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PaymentSource string
+
+const (
+	// PaymentSourceRenewal marks charges recorded by the renewal engine.
+	PaymentSourceRenewal PaymentSource = "renewal"
+	// PaymentSourceManual marks charges logged by the user.
+	PaymentSourceManual PaymentSource = "manual"
+	// PaymentSourceTrialConversion marks the first charge after a free trial.
+	PaymentSourceTrialConversion PaymentSource = "trial_conversion"
+)
+
+// Payment is a single charge made for a subscription.
+type Payment struct {
+	ID              ULID          `gorm:"primaryKey;type:char(26)"`
+	UserID          ULID          `gorm:"type:char(26);not null;index"`
+	SubscriptionID  ULID          `gorm:"type:char(26);not null;index"`
+	PaymentMethodID ULID          `gorm:"type:char(26);not null"`
+	PaymentMethod   PaymentMethod `gorm:"foreignKey:PaymentMethodID"`
+	CurrencyID      ULID          `gorm:"type:char(26);not null"`
+	Currency        Currency      `gorm:"foreignKey:CurrencyID"`
+	Amount          float64       `gorm:"type:decimal(10,2);not null"`
+	PaidAt          time.Time     `gorm:"not null;index"`
+	Source          PaymentSource `gorm:"not null;type:varchar(20)"`
+	Notes           string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
+}
@@ -29,13 +29,32 @@ func IsValidPaymentMethodType(pmType PaymentMethodType) bool {
 }
 
 type PaymentMethod struct {
-	ID        ULID              `gorm:"primaryKey;type:char(26)"`
-	UserID    ULID              `gorm:"type:char(26);not null"`
-	User      User              `gorm:"foreignKey:UserID"`
-	Name      string            `gorm:"not null"`
-	Type      PaymentMethodType `gorm:"not null;type:varchar(20)"`
-	LastFour  string            `gorm:"type:varchar(4)"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt gorm.DeletedAt `gorm:"index"`
+	ID          ULID              `gorm:"primaryKey;type:char(26)"`
+	UserID      ULID              `gorm:"type:char(26);not null"`
+	User        User              `gorm:"foreignKey:UserID"`
+	Name        string            `gorm:"not null"`
+	Type        PaymentMethodType `gorm:"not null;type:varchar(20)"`
+	LastFour    string            `gorm:"type:varchar(4)"`
+	ExpiryMonth *int              // Cards only, set together with ExpiryYear
+	ExpiryYear  *int
+	ArchivedAt  *time.Time // Archived methods are hidden from the list but stay on their subscriptions
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+// IsCardPaymentMethodType reports whether pmType is a card, the only type
+// with an expiry date.
+func IsCardPaymentMethodType(pmType PaymentMethodType) bool {
+	return pmType == PaymentMethodTypeCreditCard || pmType == PaymentMethodTypeDebitCard
+}
+
+// ExpiresAt returns when a card stops working: it is valid through the last
+// day of its expiry month. It is nil if no expiry is recorded.
+func (p *PaymentMethod) ExpiresAt() *time.Time {
+	if p.ExpiryMonth == nil || p.ExpiryYear == nil {
+		return nil
+	}
+	expiresAt := time.Date(*p.ExpiryYear, time.Month(*p.ExpiryMonth)+1, 1, 0, 0, 0, 0, time.UTC)
+	return &expiresAt
 }
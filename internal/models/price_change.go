@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PriceChange records the price of a subscription from EffectiveDate on.
+// Changes with a future effective date stay scheduled (AppliedAt is nil)
+// until the background job copies them onto the subscription.
+type PriceChange struct {
+	ID             ULID      `gorm:"primaryKey;type:char(26)"`
+	UserID         ULID      `gorm:"type:char(26);not null;index"`
+	SubscriptionID ULID      `gorm:"type:char(26);not null;index:idx_price_change_effective"`
+	Amount         float64   `gorm:"type:decimal(10,2);not null"`
+	CurrencyID     ULID      `gorm:"type:char(26);not null"`
+	Currency       Currency  `gorm:"foreignKey:CurrencyID"`
+	EffectiveDate  time.Time `gorm:"not null;index:idx_price_change_effective"`
+	AppliedAt      *time.Time
+	Note           string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+}
+
+// IsScheduled reports whether the change has not been applied yet.
+func (p *PriceChange) IsScheduled() bool {
+	return p.AppliedAt == nil
+}
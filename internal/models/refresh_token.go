@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RefreshToken lets a client obtain new access tokens without logging in
+// again. Every refresh replaces the token with a new one of the same family,
+// which starts at login; presenting a replaced token again means it was
+// stolen, and the whole family is revoked.
+type RefreshToken struct {
+	ID        ULID       `gorm:"primaryKey;type:char(26)"`
+	UserID    ULID       `gorm:"type:char(26);not null;index"`
+	FamilyID  ULID       `gorm:"type:char(26);not null;index"`
+	TokenHash string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;index"`
+	RotatedAt *time.Time // Set when the token was exchanged for a new one
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Reminder records the notification for one billing occurrence of a
+// subscription. The unique index on (SubscriptionID, BillingDate) ensures a
+// reminder fires at most once per occurrence.
+type Reminder struct {
+	ID             ULID      `gorm:"primaryKey;type:char(26)"`
+	UserID         ULID      `gorm:"type:char(26);not null;index"`
+	SubscriptionID ULID      `gorm:"type:char(26);not null;uniqueIndex:idx_reminder_occurrence"`
+	BillingDate    time.Time `gorm:"not null;uniqueIndex:idx_reminder_occurrence"`
+	RemindAt       time.Time `gorm:"not null"`
+	SentAt         *time.Time
+	Attempts       int `gorm:"not null;default:0"`
+	LastError      string
+	// Earliest time a failed reminder is retried; nil until a delivery fails.
+	NextAttemptAt *time.Time `gorm:"index"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"errors"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
@@ -22,9 +24,131 @@ type Subscription struct {
 	Description     string
 	Amount          float64   `gorm:"type:decimal(10,2);not null"`
 	NextBillingDate time.Time `gorm:"not null"`
-	ReminderDays    int       `gorm:"default:7"`
-	Active          bool      `gorm:"default:true"`
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
-	DeletedAt       gorm.DeletedAt `gorm:"index"`
+	// Day of month renewals are anchored to, so month-end dates that get
+	// clamped (Jan 31 -> Feb 28) return to the original day afterwards.
+	BillingAnchorDay int                `gorm:"not null;default:0"`
+	ReminderDays     int                `gorm:"default:7"`
+	Status           SubscriptionStatus `gorm:"type:varchar(30);not null;default:'active';index"`
+	// Free trial. While trialing, Amount is the trial price and the
+	// subscription converts to PostTrialAmount when TrialEndDate passes.
+	TrialStartDate   *time.Time
+	TrialEndDate     *time.Time `gorm:"index"`
+	PostTrialAmount  *float64   `gorm:"type:decimal(10,2)"`
+	TrialConvertedAt *time.Time
+	// Date a cancellation takes effect; the subscription is no longer
+	// billed from this date on. Cleared when the subscription is resumed.
+	CancelsAt *time.Time `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// AnchorDay returns the day of month the subscription renews on, falling back
+// to the day of NextBillingDate for rows created before anchors were stored.
+func (s *Subscription) AnchorDay() int {
+	if s.BillingAnchorDay > 0 {
+		return s.BillingAnchorDay
+	}
+	return s.NextBillingDate.Day()
+}
+
+// IsTrialing reports whether the subscription is in a trial that has not yet
+// converted to a paid plan.
+func (s *Subscription) IsTrialing() bool {
+	return s.Status == SubscriptionStatusTrialing
+}
+
+// BilledAmount is the amount charged on the next billing date. A trialing
+// subscription is next billed at its post-trial price.
+func (s *Subscription) BilledAmount() float64 {
+	if s.IsTrialing() && s.PostTrialAmount != nil {
+		return *s.PostTrialAmount
+	}
+	return s.Amount
+}
+
+// BilledOn reports whether the subscription is still charged on date, i.e.
+// date falls before a pending cancellation takes effect.
+func (s *Subscription) BilledOn(date time.Time) bool {
+	return s.CancelsAt == nil || date.Before(*s.CancelsAt)
+}
+
+// maxOccurrences guards projections against runaway loops, e.g. a daily
+// cycle expanded over many years.
+const maxOccurrences = 1000
+
+// ErrTooManyOccurrences is returned when a projection would expand more than
+// maxOccurrences billing dates.
+var ErrTooManyOccurrences = errors.New("too many billing dates to project")
+
+// OccurrencesBetween expands the subscription's billing cycle from
+// NextBillingDate and returns the billing dates that fall within [from, to],
+// stopping at CancelsAt. BillingCycle must be loaded.
+//
+// Open pause windows of the subscription may be passed to project them: the
+// first billing date on or after a window's start is pushed back by the
+// days paused, as happens when the subscription is resumed, and a window
+// without an end date stops the projection.
+func (s *Subscription) OccurrencesBetween(from, to time.Time, pauses ...PauseWindow) ([]time.Time, error) {
+	var occurrences []time.Time
+	steps := 0
+	tooMany := false
+	s.walk(to, pauses, func(date time.Time) bool {
+		if len(occurrences) == maxOccurrences || steps == maxOccurrences*10 {
+			tooMany = true
+			return false
+		}
+		steps++
+		if !date.Before(from) {
+			occurrences = append(occurrences, date)
+		}
+		return true
+	})
+	if tooMany {
+		return nil, ErrTooManyOccurrences
+	}
+	return occurrences, nil
+}
+
+// EachOccurrence calls fn with the billing dates OccurrencesBetween would
+// return, in order, stopping early when fn returns false. The dates are not
+// collected, so unlike OccurrencesBetween it is not limited to
+// maxOccurrences of them; it suits counting or totalling long ranges.
+func (s *Subscription) EachOccurrence(from, to time.Time, fn func(date time.Time) bool, pauses ...PauseWindow) {
+	s.walk(to, pauses, func(date time.Time) bool {
+		return date.Before(from) || fn(date)
+	})
+}
+
+// walk calls fn with every billing date from NextBillingDate up to to,
+// applying pauses, until fn returns false.
+func (s *Subscription) walk(to time.Time, pauses []PauseWindow, fn func(date time.Time) bool) {
+	pauses = append([]PauseWindow(nil), pauses...)
+	sort.Slice(pauses, func(i, j int) bool {
+		return pauses[i].StartDate.Before(pauses[j].StartDate)
+	})
+
+	date := s.NextBillingDate
+	for {
+		for len(pauses) > 0 && !pauses[0].StartDate.After(date) {
+			if pauses[0].EndDate == nil {
+				return
+			}
+			location := date.Location()
+			date = date.AddDate(0, 0, CalendarDaysBetween(pauses[0].StartDate.In(location), pauses[0].EndDate.In(location)))
+			pauses = pauses[1:]
+		}
+		if date.After(to) || !s.BilledOn(date) || !fn(date) {
+			return
+		}
+		date = s.BillingCycle.NextDate(date, s.AnchorDay())
+	}
+}
+
+// CalendarDaysBetween counts the calendar days from the date of from to the
+// date of to, regardless of the time of day and daylight saving changes.
+func CalendarDaysBetween(from, to time.Time) int {
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDate.Sub(fromDate).Hours() / 24)
 }
@@ -0,0 +1,97 @@
+package models
+
+type SubscriptionStatus string
+
+const (
+	SubscriptionStatusDraft               SubscriptionStatus = "draft"
+	SubscriptionStatusTrialing            SubscriptionStatus = "trialing"
+	SubscriptionStatusActive              SubscriptionStatus = "active"
+	SubscriptionStatusPaused              SubscriptionStatus = "paused"
+	SubscriptionStatusPastDue             SubscriptionStatus = "past_due"
+	SubscriptionStatusPendingCancellation SubscriptionStatus = "pending_cancellation"
+	SubscriptionStatusCancelled           SubscriptionStatus = "cancelled"
+	SubscriptionStatusExpired             SubscriptionStatus = "expired"
+)
+
+// subscriptionTransitions lists the statuses each status may move to.
+// Cancelled and expired subscriptions are final.
+var subscriptionTransitions = map[SubscriptionStatus][]SubscriptionStatus{
+	// Drafts are proposed by a statement import and wait for the user to
+	// confirm them; discarding a draft deletes it.
+	SubscriptionStatusDraft: {
+		SubscriptionStatusActive,
+	},
+	SubscriptionStatusTrialing: {
+		SubscriptionStatusActive,
+		SubscriptionStatusPendingCancellation,
+		SubscriptionStatusCancelled,
+		SubscriptionStatusExpired,
+	},
+	SubscriptionStatusActive: {
+		SubscriptionStatusPaused,
+		SubscriptionStatusPastDue,
+		SubscriptionStatusPendingCancellation,
+		SubscriptionStatusCancelled,
+		SubscriptionStatusExpired,
+	},
+	SubscriptionStatusPaused: {
+		SubscriptionStatusActive,
+		SubscriptionStatusCancelled,
+		SubscriptionStatusExpired,
+	},
+	SubscriptionStatusPastDue: {
+		SubscriptionStatusActive,
+		SubscriptionStatusPaused,
+		SubscriptionStatusCancelled,
+		SubscriptionStatusExpired,
+	},
+	SubscriptionStatusPendingCancellation: {
+		SubscriptionStatusActive,
+		SubscriptionStatusCancelled,
+		SubscriptionStatusExpired,
+	},
+}
+
+// LiveStatuses are the statuses of subscriptions that are still in use and
+// show up in active listings, projections and totals.
+var LiveStatuses = []SubscriptionStatus{
+	SubscriptionStatusTrialing,
+	SubscriptionStatusActive,
+	SubscriptionStatusPastDue,
+	SubscriptionStatusPendingCancellation,
+}
+
+// RenewableStatuses are the statuses the renewal engine rolls forward.
+// Trials are converted separately and past-due subscriptions wait until
+// they are resumed.
+var RenewableStatuses = []SubscriptionStatus{
+	SubscriptionStatusActive,
+	SubscriptionStatusPendingCancellation,
+}
+
+func IsValidSubscriptionStatus(status SubscriptionStatus) bool {
+	if _, ok := subscriptionTransitions[status]; ok {
+		return true
+	}
+	return status == SubscriptionStatusCancelled || status == SubscriptionStatusExpired
+}
+
+// CanTransitionTo reports whether a subscription may move from s to next.
+func (s SubscriptionStatus) CanTransitionTo(next SubscriptionStatus) bool {
+	for _, allowed := range subscriptionTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLive reports whether the status is one of LiveStatuses.
+func (s SubscriptionStatus) IsLive() bool {
+	for _, status := range LiveStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
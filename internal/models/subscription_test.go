@@ -0,0 +1,196 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionOccurrencesBetween(t *testing.T) {
+	monthly := BillingCycle{Interval: 1, Unit: BillingCycleUnitMonth}
+	cancelsAt := date(2024, 4, 30)
+
+	pauseEnd := date(2024, 3, 20)
+
+	tests := []struct {
+		name         string
+		subscription Subscription
+		from, to     time.Time
+		pauses       []PauseWindow
+		want         []time.Time
+	}{
+		{
+			name:         "monthly within range",
+			subscription: Subscription{NextBillingDate: date(2024, 1, 15), BillingCycle: monthly},
+			from:         date(2024, 1, 1),
+			to:           date(2024, 3, 31),
+			want:         []time.Time{date(2024, 1, 15), date(2024, 2, 15), date(2024, 3, 15)},
+		},
+		{
+			name:         "bounds are inclusive",
+			subscription: Subscription{NextBillingDate: date(2024, 1, 15), BillingCycle: monthly},
+			from:         date(2024, 2, 15),
+			to:           date(2024, 3, 15),
+			want:         []time.Time{date(2024, 2, 15), date(2024, 3, 15)},
+		},
+		{
+			name:         "next billing date after range",
+			subscription: Subscription{NextBillingDate: date(2024, 6, 1), BillingCycle: monthly},
+			from:         date(2024, 1, 1),
+			to:           date(2024, 3, 31),
+			want:         nil,
+		},
+		{
+			name:         "month end anchor is kept after clamping",
+			subscription: Subscription{NextBillingDate: date(2024, 1, 31), BillingAnchorDay: 31, BillingCycle: monthly},
+			from:         date(2024, 1, 1),
+			to:           date(2024, 4, 30),
+			want:         []time.Time{date(2024, 1, 31), date(2024, 2, 29), date(2024, 3, 31), date(2024, 4, 30)},
+		},
+		{
+			name:         "anchor defaults to next billing date",
+			subscription: Subscription{NextBillingDate: date(2024, 1, 31), BillingCycle: monthly},
+			from:         date(2024, 1, 1),
+			to:           date(2024, 3, 31),
+			want:         []time.Time{date(2024, 1, 31), date(2024, 2, 29), date(2024, 3, 31)},
+		},
+		{
+			name:         "stops at cancellation",
+			subscription: Subscription{NextBillingDate: date(2024, 1, 30), BillingCycle: monthly, CancelsAt: &cancelsAt},
+			from:         date(2024, 1, 1),
+			to:           date(2024, 12, 31),
+			want:         []time.Time{date(2024, 1, 30), date(2024, 2, 29), date(2024, 3, 30)},
+		},
+		{
+			name:         "dates before range are skipped",
+			subscription: Subscription{NextBillingDate: date(2023, 11, 5), BillingCycle: BillingCycle{Interval: 2, Unit: BillingCycleUnitWeek}},
+			from:         date(2024, 1, 1),
+			to:           date(2024, 1, 31),
+			want:         []time.Time{date(2024, 1, 14), date(2024, 1, 28)},
+		},
+		{
+			name:         "pause shifts the date it covers and keeps the anchor",
+			subscription: Subscription{NextBillingDate: date(2024, 1, 15), BillingCycle: monthly},
+			from:         date(2024, 1, 1),
+			to:           date(2024, 4, 30),
+			pauses:       []PauseWindow{{StartDate: date(2024, 2, 10), EndDate: &pauseEnd}},
+			want:         []time.Time{date(2024, 1, 15), date(2024, 3, 25), date(2024, 4, 15)},
+		},
+		{
+			name:         "open ended pause stops billing",
+			subscription: Subscription{NextBillingDate: date(2024, 1, 15), BillingCycle: monthly},
+			from:         date(2024, 1, 1),
+			to:           date(2024, 12, 31),
+			pauses:       []PauseWindow{{StartDate: date(2024, 3, 1)}},
+			want:         []time.Time{date(2024, 1, 15), date(2024, 2, 15)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.subscription.OccurrencesBetween(tt.from, tt.to, tt.pauses...)
+			if err != nil {
+				t.Fatalf("OccurrencesBetween() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("OccurrencesBetween() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %s, want %s", i, got[i].Format(time.DateOnly), tt.want[i].Format(time.DateOnly))
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriptionOccurrencesBetweenTooMany(t *testing.T) {
+	daily := BillingCycle{Interval: 1, Unit: BillingCycleUnitDay}
+
+	tests := []struct {
+		name         string
+		subscription Subscription
+		from, to     time.Time
+		wantErr      bool
+	}{
+		{"at the limit", Subscription{NextBillingDate: date(2024, 1, 1), BillingCycle: daily}, date(2024, 1, 1), date(2024, 1, 1).AddDate(0, 0, maxOccurrences-1), false},
+		{"over the limit", Subscription{NextBillingDate: date(2024, 1, 1), BillingCycle: daily}, date(2024, 1, 1), date(2024, 1, 1).AddDate(0, 0, maxOccurrences), true},
+		{"too many dates before range", Subscription{NextBillingDate: date(1980, 1, 1), BillingCycle: daily}, date(2024, 1, 1), date(2024, 1, 2), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.subscription.OccurrencesBetween(tt.from, tt.to)
+			if tt.wantErr {
+				if !errors.Is(err, ErrTooManyOccurrences) {
+					t.Fatalf("OccurrencesBetween() error = %v, want ErrTooManyOccurrences", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OccurrencesBetween() error = %v", err)
+			}
+			if len(got) != maxOccurrences {
+				t.Errorf("OccurrencesBetween() returned %d dates, want %d", len(got), maxOccurrences)
+			}
+		})
+	}
+}
+
+func TestSubscriptionEachOccurrence(t *testing.T) {
+	daily := BillingCycle{Interval: 1, Unit: BillingCycleUnitDay}
+	monthly := BillingCycle{Interval: 1, Unit: BillingCycleUnitMonth}
+	cancelsAt := date(2024, 4, 30)
+	pauseEnd := date(2024, 3, 20)
+
+	tests := []struct {
+		name         string
+		subscription Subscription
+		from, to     time.Time
+		pauses       []PauseWindow
+		want         int
+	}{
+		{"beyond the projection limit", Subscription{NextBillingDate: date(1980, 1, 1), BillingCycle: daily}, date(1980, 1, 1), date(1989, 12, 31), nil, 3653},
+		{"dates before range skipped", Subscription{NextBillingDate: date(1980, 1, 1), BillingCycle: daily}, date(2024, 1, 1), date(2024, 1, 2), nil, 2},
+		{"stops at cancellation", Subscription{NextBillingDate: date(2024, 1, 15), BillingCycle: monthly, CancelsAt: &cancelsAt}, date(2024, 1, 1), date(2024, 12, 31), nil, 4},
+		{"pause pushes dates back", Subscription{NextBillingDate: date(2024, 1, 15), BillingCycle: monthly}, date(2024, 1, 1), date(2024, 4, 30), []PauseWindow{{StartDate: date(2024, 2, 10), EndDate: &pauseEnd}}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []time.Time
+			tt.subscription.EachOccurrence(tt.from, tt.to, func(date time.Time) bool {
+				got = append(got, date)
+				return true
+			}, tt.pauses...)
+			if len(got) != tt.want {
+				t.Fatalf("EachOccurrence() yielded %d dates, want %d", len(got), tt.want)
+			}
+			want, err := tt.subscription.OccurrencesBetween(tt.from, tt.to, tt.pauses...)
+			if errors.Is(err, ErrTooManyOccurrences) {
+				return
+			}
+			if err != nil {
+				t.Fatalf("OccurrencesBetween() error = %v", err)
+			}
+			for i := range want {
+				if !got[i].Equal(want[i]) {
+					t.Errorf("date %d = %s, want %s", i, got[i].Format(time.DateOnly), want[i].Format(time.DateOnly))
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriptionEachOccurrenceStops(t *testing.T) {
+	subscription := Subscription{NextBillingDate: date(2024, 1, 1), BillingCycle: BillingCycle{Interval: 1, Unit: BillingCycleUnitDay}}
+
+	calls := 0
+	subscription.EachOccurrence(date(2024, 1, 1), date(2024, 12, 31), func(time.Time) bool {
+		calls++
+		return calls < 3
+	})
+	if calls != 3 {
+		t.Errorf("fn called %d times, want 3", calls)
+	}
+}
@@ -8,16 +8,24 @@ import (
 )
 
 type User struct {
-	ID             ULID            `gorm:"primaryKey;type:char(26)"`
-	Email          string          `gorm:"uniqueIndex;not null"`
-	PasswordHash   string          `gorm:"not null"`
-	Name           string          `gorm:"not null"`
-	Categories     []Category      `gorm:"foreignKey:UserID"`
-	Subscriptions  []Subscription  `gorm:"foreignKey:UserID"`
-	PaymentMethods []PaymentMethod `gorm:"foreignKey:UserID"`
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	ID              ULID            `gorm:"primaryKey;type:char(26)"`
+	Email           string          `gorm:"uniqueIndex;not null"`
+	PasswordHash    string          `gorm:"not null"`
+	Name            string          `gorm:"not null"`
+	IsAdmin         bool            `gorm:"not null;default:false"` // Granted through ADMIN_EMAILS
+	BaseCurrencyID  *ULID           `gorm:"type:char(26)"`          // Currency totals are converted to; nil leaves amounts unconverted
+	BaseCurrency    *Currency       `gorm:"foreignKey:BaseCurrencyID"`
+	Timezone        string          `gorm:"not null;default:'UTC'"`   // IANA name, e.g. Europe/Berlin
+	Locale          string          `gorm:"not null;default:'en-US'"` // BCP 47 tag
+	TokenVersion    int             `gorm:"not null;default:0"`       // Carried by access tokens; bumped to revoke them all, e.g. after a password change
+	TokensRevokedAt *time.Time      // When TokenVersion was last bumped
+	DeletesAt       *time.Time      `gorm:"index"` // Account and data are purged from this date; cleared on restore
+	Categories      []Category      `gorm:"foreignKey:UserID"`
+	Subscriptions   []Subscription  `gorm:"foreignKey:UserID"`
+	PaymentMethods  []PaymentMethod `gorm:"foreignKey:UserID"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,25 @@
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+// LogNotifier writes messages to the application log. Useful in development.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, msg Message) error {
+	log.Printf("Notification to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// NoopNotifier discards every message.
+type NoopNotifier struct{}
+
+func (NoopNotifier) Notify(ctx context.Context, msg Message) error {
+	return nil
+}
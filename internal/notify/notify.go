@@ -0,0 +1,35 @@
+// Package notify delivers user notifications such as renewal reminders.
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"subscription-tracker/internal/config"
+)
+
+// Message is a plain-text notification addressed to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages. Implementations must be safe for concurrent use.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// New returns the notifier selected by cfg.Notifier.Driver.
+func New(cfg *config.Config) (Notifier, error) {
+	switch cfg.Notifier.Driver {
+	case "", "log":
+		return NewLogNotifier(), nil
+	case "noop":
+		return NoopNotifier{}, nil
+	case "smtp":
+		return NewSMTPNotifier(cfg.Notifier.SMTP), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier driver %q", cfg.Notifier.Driver)
+	}
+}
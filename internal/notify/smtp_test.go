@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"subscription-tracker/internal/config"
+)
+
+// smtpSession is what the stub server received during one session.
+type smtpSession struct {
+	from string
+	to   []string
+	data string
+}
+
+// serveSMTP answers a single SMTP session on l, advertising neither
+// STARTTLS nor AUTH, and sends what it received on the returned channel.
+func serveSMTP(t *testing.T, l net.Listener) <-chan smtpSession {
+	t.Helper()
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var session smtpSession
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP stub")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch {
+			case verb == "EHLO" || verb == "HELO":
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+				// Drop ESMTP parameters such as BODY=8BITMIME.
+				session.from = strings.Fields(line[len("MAIL FROM:"):])[0]
+				reply("250 OK")
+			case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+				session.to = append(session.to, line[len("RCPT TO:"):])
+				reply("250 OK")
+			case verb == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				session.data = data.String()
+				reply("250 OK")
+			case verb == "QUIT":
+				reply("221 Bye")
+				sessions <- session
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+	return sessions
+}
+
+func listen(t *testing.T) (net.Listener, config.SMTPConfig) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	return l, config.SMTPConfig{
+		Host: host,
+		Port: port,
+		From: "Subscription Tracker <no-reply@example.com>",
+	}
+}
+
+func TestSMTPNotifierNotify(t *testing.T) {
+	l, cfg := listen(t)
+	sessions := serveSMTP(t, l)
+
+	msg := Message{
+		To:      "jane@example.com",
+		Subject: "Netflix renews on März 3",
+		Body:    "Hi Jane,\n\nNetflix renews soon.",
+	}
+	if err := NewSMTPNotifier(cfg).Notify(context.Background(), msg); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	var session smtpSession
+	select {
+	case session = <-sessions:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stub server did not receive a complete session")
+	}
+
+	if session.from != "<no-reply@example.com>" {
+		t.Errorf("MAIL FROM = %q, want the bare sender address", session.from)
+	}
+	if len(session.to) != 1 || session.to[0] != "<jane@example.com>" {
+		t.Errorf("RCPT TO = %q, want [<jane@example.com>]", session.to)
+	}
+
+	headers, body, ok := strings.Cut(session.data, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("message has no header/body separator:\n%s", session.data)
+	}
+	for _, want := range []string{
+		"From: Subscription Tracker <no-reply@example.com>\r\n",
+		"To: jane@example.com\r\n",
+		"Subject: =?utf-8?q?Netflix_renews_on_M=C3=A4rz_3?=\r\n",
+		"Content-Type: text/plain; charset=UTF-8\r\n",
+	} {
+		if !strings.Contains(headers+"\r\n", want) {
+			t.Errorf("headers missing %q:\n%s", want, headers)
+		}
+	}
+	if want := "Hi Jane,\r\n\r\nNetflix renews soon.\r\n"; body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestSMTPNotifierNotifyHonoursContext(t *testing.T) {
+	l, cfg := listen(t)
+	// Accept the connection but never greet, like a hung server.
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(5 * time.Second)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := NewSMTPNotifier(cfg).Notify(ctx, Message{To: "jane@example.com", Subject: "Hi", Body: "Hi"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Notify() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Notify() returned after %v, want it to stop at the deadline", elapsed)
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"subscription-tracker/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// monthlyFactorSQL converts one billing period of the joined billing cycle
+// into months, e.g. 1/12 for a yearly cycle and 365/7/12 for a weekly one.
+const monthlyFactorSQL = `(CASE billing_cycles.unit
+	WHEN 'day' THEN 365.0 / 12
+	WHEN 'week' THEN 365.0 / 7 / 12
+	WHEN 'year' THEN 1.0 / 12
+	ELSE 1.0
+END / GREATEST(billing_cycles.interval_count, 1))`
+
+// billedAmountSQL is the amount charged per period, using the post-trial
+// price for subscriptions still in their trial.
+const billedAmountSQL = `(CASE WHEN subscriptions.status = 'trialing' AND subscriptions.post_trial_amount IS NOT NULL
+	THEN subscriptions.post_trial_amount
+	ELSE subscriptions.amount
+END)`
+
+type AnalyticsRepository struct {
+	db *gorm.DB
+}
+
+// SpendTotal is the normalized cost of the live subscriptions in one group,
+// in a single currency.
+type SpendTotal struct {
+	GroupID      models.ULID `json:"groupId"`
+	GroupName    string      `json:"groupName"`
+	CurrencyID   models.ULID `json:"currencyId"`
+	CurrencyCode string      `json:"currencyCode"`
+	Monthly      float64     `json:"monthly"`
+	Yearly       float64     `json:"yearly"`
+	Count        int64       `json:"count"`
+}
+
+func NewAnalyticsRepository(db *gorm.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// SpendByCategory sums the normalized cost of the user's live subscriptions
+// per category and currency.
+func (r *AnalyticsRepository) SpendByCategory(userID models.ULID) ([]SpendTotal, error) {
+	return r.spendBy(userID, "categories", "subscriptions.category_id")
+}
+
+// SpendByPaymentMethod sums the normalized cost of the user's live
+// subscriptions per payment method and currency.
+func (r *AnalyticsRepository) SpendByPaymentMethod(userID models.ULID) ([]SpendTotal, error) {
+	return r.spendBy(userID, "payment_methods", "subscriptions.payment_method_id")
+}
+
+// SpendByCurrency sums the normalized cost of the user's live subscriptions
+// per currency.
+func (r *AnalyticsRepository) SpendByCurrency(userID models.ULID) ([]SpendTotal, error) {
+	return r.spendBy(userID, "currencies", "subscriptions.currency_id")
+}
+
+// spendBy groups on the name of the table referenced by foreignKey. Soft
+// deleted categories and payment methods still count towards the totals of
+// the subscriptions using them.
+func (r *AnalyticsRepository) spendBy(userID models.ULID, table, foreignKey string) ([]SpendTotal, error) {
+	groupName := table + ".name"
+	if table == "currencies" {
+		groupName = "currencies.code"
+	}
+
+	query := r.db.Model(&models.Subscription{}).
+		Select(
+			foreignKey + " AS group_id, " + groupName + " AS group_name, " +
+				"subscriptions.currency_id, currencies.code AS currency_code, " +
+				"ROUND(SUM(" + billedAmountSQL + " * " + monthlyFactorSQL + "), 2) AS monthly, " +
+				"ROUND(SUM(" + billedAmountSQL + " * " + monthlyFactorSQL + " * 12), 2) AS yearly, " +
+				"COUNT(*) AS count",
+		).
+		Joins("JOIN billing_cycles ON billing_cycles.id = subscriptions.billing_cycle_id").
+		Joins("JOIN currencies ON currencies.id = subscriptions.currency_id")
+	if table != "currencies" {
+		query = query.Joins("JOIN " + table + " ON " + table + ".id = " + foreignKey)
+	}
+
+	var totals []SpendTotal
+	err := query.
+		Where("subscriptions.user_id = $1 AND subscriptions.status = ANY($2)", userID, models.LiveStatuses).
+		Group(foreignKey + ", " + groupName + ", subscriptions.currency_id, currencies.code").
+		Order("monthly DESC").
+		Scan(&totals).Error
+	return totals, err
+}
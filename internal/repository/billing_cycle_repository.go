@@ -4,6 +4,7 @@ import (
 	"subscription-tracker/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BillingCycleRepository struct {
@@ -14,6 +15,11 @@ func NewBillingCycleRepository(db *gorm.DB) *BillingCycleRepository {
 	return &BillingCycleRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *BillingCycleRepository) WithTx(tx *gorm.DB) *BillingCycleRepository {
+	return &BillingCycleRepository{db: tx}
+}
+
 func (r *BillingCycleRepository) Create(billingCycle *models.BillingCycle) error {
 	return r.db.Create(billingCycle).Error
 }
@@ -27,6 +33,20 @@ func (r *BillingCycleRepository) GetByID(id models.ULID) (*models.BillingCycle,
 	return &billingCycle, nil
 }
 
+// GetByIDsUnscoped returns the billing cycles with the given IDs, including
+// soft-deleted ones that existing subscriptions may still reference.
+func (r *BillingCycleRepository) GetByIDsUnscoped(ids []models.ULID) ([]models.BillingCycle, error) {
+	var billingCycles []models.BillingCycle
+	if len(ids) == 0 {
+		return billingCycles, nil
+	}
+	err := r.db.Unscoped().Where("id = ANY($1)", ids).Find(&billingCycles).Error
+	if err != nil {
+		return nil, err
+	}
+	return billingCycles, nil
+}
+
 func (r *BillingCycleRepository) GetAllForUser(userID models.ULID) ([]models.BillingCycle, error) {
 	var billingCycles []models.BillingCycle
 	err := r.db.Where("user_id = $1 OR system_defined = $2", userID, true).
@@ -38,6 +58,30 @@ func (r *BillingCycleRepository) GetAllForUser(userID models.ULID) ([]models.Bil
 	return billingCycles, nil
 }
 
+// LockForShare returns the billing cycle with the given ID, locking it against
+// updates and deletes until the transaction ends.
+func (r *BillingCycleRepository) LockForShare(id models.ULID) (*models.BillingCycle, error) {
+	var billingCycle models.BillingCycle
+	err := r.db.Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("id = $1", id).
+		First(&billingCycle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &billingCycle, nil
+}
+
+// LockByIDs locks the billing cycles with the given IDs until the transaction
+// ends, in ID order so that concurrent callers cannot deadlock.
+func (r *BillingCycleRepository) LockByIDs(ids ...models.ULID) ([]models.BillingCycle, error) {
+	var billingCycles []models.BillingCycle
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ANY($1)", ids).
+		Order("id").
+		Find(&billingCycles).Error
+	return billingCycles, err
+}
+
 func (r *BillingCycleRepository) Update(billingCycle *models.BillingCycle) error {
 	return r.db.Save(billingCycle).Error
 }
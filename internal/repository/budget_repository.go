@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"subscription-tracker/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type BudgetRepository struct {
+	db *gorm.DB
+}
+
+func NewBudgetRepository(db *gorm.DB) *BudgetRepository {
+	return &BudgetRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *BudgetRepository) WithTx(tx *gorm.DB) *BudgetRepository {
+	return &BudgetRepository{db: tx}
+}
+
+func (r *BudgetRepository) Create(budget *models.Budget) error {
+	return r.db.Create(budget).Error
+}
+
+func (r *BudgetRepository) GetByID(id, userID models.ULID) (*models.Budget, error) {
+	var budget models.Budget
+	err := r.db.Where("id = $1 AND user_id = $2", id, userID).
+		Preload("Category").
+		Preload("Currency").
+		First(&budget).Error
+	if err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+// GetByCategory returns the user's budget for a category.
+func (r *BudgetRepository) GetByCategory(categoryID, userID models.ULID) (*models.Budget, error) {
+	var budget models.Budget
+	err := r.db.Where("category_id = $1 AND user_id = $2", categoryID, userID).
+		Preload("Category").
+		Preload("Currency").
+		First(&budget).Error
+	if err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func (r *BudgetRepository) GetAllForUser(userID models.ULID) ([]models.Budget, error) {
+	var budgets []models.Budget
+	err := r.db.Where("user_id = $1", userID).
+		Preload("Category").
+		Preload("Currency").
+		Order("created_at ASC").
+		Find(&budgets).Error
+	return budgets, err
+}
+
+func (r *BudgetRepository) Update(budget *models.Budget) error {
+	return r.db.Save(budget).Error
+}
+
+func (r *BudgetRepository) Delete(budget *models.Budget) error {
+	return r.db.Delete(budget).Error
+}
+
+// DeleteByCategory removes the budgets of a category.
+func (r *BudgetRepository) DeleteByCategory(categoryID models.ULID) error {
+	return r.db.Where("category_id = ?", categoryID).Delete(&models.Budget{}).Error
+}
+
+func (r *BudgetRepository) ExistsForCategory(categoryID, userID models.ULID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Budget{}).
+		Where("category_id = $1 AND user_id = $2", categoryID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"subscription-tracker/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CalendarTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewCalendarTokenRepository(db *gorm.DB) *CalendarTokenRepository {
+	return &CalendarTokenRepository{db: db}
+}
+
+func (r *CalendarTokenRepository) Create(token *models.CalendarToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetActiveByHash finds a token that has not been revoked.
+func (r *CalendarTokenRepository) GetActiveByHash(hash string) (*models.CalendarToken, error) {
+	var token models.CalendarToken
+	err := r.db.Where("token_hash = $1 AND revoked_at IS NULL", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeAllForUser revokes every active token of the user. The condition
+// uses ? placeholders: GORM numbers the SET values of an UPDATE first, so a
+// literal $1 would refer to revoked_at.
+func (r *CalendarTokenRepository) RevokeAllForUser(userID models.ULID, at time.Time) error {
+	return r.db.Model(&models.CalendarToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+func (r *CalendarTokenRepository) TouchLastUsed(token *models.CalendarToken, at time.Time) error {
+	return r.db.Model(token).Update("last_used_at", at).Error
+}
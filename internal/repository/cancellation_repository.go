@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"subscription-tracker/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CancellationRepository struct {
+	db *gorm.DB
+}
+
+func NewCancellationRepository(db *gorm.DB) *CancellationRepository {
+	return &CancellationRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *CancellationRepository) WithTx(tx *gorm.DB) *CancellationRepository {
+	return &CancellationRepository{db: tx}
+}
+
+func (r *CancellationRepository) Create(cancellation *models.Cancellation) error {
+	return r.db.Create(cancellation).Error
+}
+
+// GetCurrent returns the subscription's latest cancellation that has not been
+// withdrawn.
+func (r *CancellationRepository) GetCurrent(subscriptionID, userID models.ULID) (*models.Cancellation, error) {
+	var cancellation models.Cancellation
+	err := r.db.Where("subscription_id = $1 AND user_id = $2 AND withdrawn_at IS NULL", subscriptionID, userID).
+		Preload("Currency").
+		Order("cancelled_at DESC").
+		First(&cancellation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cancellation, nil
+}
+
+// GetAllCurrent returns the user's cancellations that have not been
+// withdrawn, most recent first.
+func (r *CancellationRepository) GetAllCurrent(userID models.ULID) ([]models.Cancellation, error) {
+	var cancellations []models.Cancellation
+	err := r.db.Where("user_id = $1 AND withdrawn_at IS NULL", userID).
+		Preload("Currency").
+		Order("cancelled_at DESC").
+		Find(&cancellations).Error
+	return cancellations, err
+}
+
+// GetAllForUser returns the user's cancellations, including withdrawn ones,
+// oldest first.
+func (r *CancellationRepository) GetAllForUser(userID models.ULID) ([]models.Cancellation, error) {
+	var cancellations []models.Cancellation
+	err := r.db.Where("user_id = $1", userID).
+		Preload("Currency").
+		Order("cancelled_at ASC").
+		Find(&cancellations).Error
+	return cancellations, err
+}
+
+// WithdrawAll marks every open cancellation of the subscription as withdrawn.
+func (r *CancellationRepository) WithdrawAll(subscriptionID models.ULID, at time.Time) error {
+	return r.db.Model(&models.Cancellation{}).
+		Where("subscription_id = ? AND withdrawn_at IS NULL", subscriptionID).
+		Update("withdrawn_at", at).Error
+}
@@ -4,6 +4,7 @@ import (
 	"subscription-tracker/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CategoryRepository struct {
@@ -14,6 +15,11 @@ func NewCategoryRepository(db *gorm.DB) *CategoryRepository {
 	return &CategoryRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *CategoryRepository) WithTx(tx *gorm.DB) *CategoryRepository {
+	return &CategoryRepository{db: tx}
+}
+
 func (r *CategoryRepository) Create(category *models.Category) error {
 	return r.db.Create(category).Error
 }
@@ -54,6 +60,30 @@ func (r *CategoryRepository) GetByID(id models.ULID) (*models.Category, error) {
 	return &category, nil
 }
 
+// LockForShare returns the category with the given ID, locking it against
+// updates and deletes until the transaction ends.
+func (r *CategoryRepository) LockForShare(id models.ULID) (*models.Category, error) {
+	var category models.Category
+	err := r.db.Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("id = $1", id).
+		First(&category).Error
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// LockByIDs locks the categories with the given IDs until the transaction
+// ends, in ID order so that concurrent callers cannot deadlock.
+func (r *CategoryRepository) LockByIDs(ids ...models.ULID) ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ANY($1)", ids).
+		Order("id").
+		Find(&categories).Error
+	return categories, err
+}
+
 func (r *CategoryRepository) Update(category *models.Category) error {
 	return r.db.Save(category).Error
 }
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"subscription-tracker/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DataExportRepository struct {
+	db *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *DataExportRepository) WithTx(tx *gorm.DB) *DataExportRepository {
+	return &DataExportRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction.
+func (r *DataExportRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+func (r *DataExportRepository) Create(export *models.DataExport) error {
+	return r.db.Create(export).Error
+}
+
+// GetLatest returns the user's most recent export without its archive.
+func (r *DataExportRepository) GetLatest(userID models.ULID) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.Omit("archive").
+		Where("user_id = $1", userID).
+		Order("created_at DESC").
+		First(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetLatestReady returns the user's most recent unexpired export that is
+// ready, including its archive.
+func (r *DataExportRepository) GetLatestReady(userID models.ULID, now time.Time) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.Where("user_id = $1 AND status = $2 AND expires_at > $3", userID, models.DataExportStatusReady, now).
+		Order("created_at DESC").
+		First(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetPending returns the user's export that is waiting to be built, if any.
+func (r *DataExportRepository) GetPending(userID models.ULID) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.Omit("archive").
+		Where("user_id = $1 AND status = $2", userID, models.DataExportStatusPending).
+		First(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// LockPending locks up to limit exports waiting to be built, oldest first.
+// Locked rows are skipped so several workers can build exports at once.
+func (r *DataExportRepository) LockPending(limit int) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Omit("archive").
+		Where("status = $1", models.DataExportStatusPending).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&exports).Error
+	return exports, err
+}
+
+// UpdateFields saves only the named columns of the export.
+func (r *DataExportRepository) UpdateFields(export *models.DataExport, fields ...string) error {
+	return r.db.Model(export).Select(fields).Updates(export).Error
+}
+
+// DeleteExpired removes exports whose archive has expired and returns how
+// many were removed.
+func (r *DataExportRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= $1", now).Delete(&models.DataExport{})
+	return result.RowsAffected, result.Error
+}
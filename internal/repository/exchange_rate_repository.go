@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"subscription-tracker/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExchangeRateRepository struct {
+	db *gorm.DB
+}
+
+func NewExchangeRateRepository(db *gorm.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *ExchangeRateRepository) WithTx(tx *gorm.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction.
+func (r *ExchangeRateRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+// Upsert stores a rate, replacing the rate of the pair already recorded for
+// the same date.
+func (r *ExchangeRateRepository) Upsert(rate *models.ExchangeRate) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base_currency_id"}, {Name: "quote_currency_id"}, {Name: "rate_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "import_id", "updated_at"}),
+	}).Create(rate).Error
+}
+
+// GetLatest returns the most recent rate of every currency pair dated on or
+// before asOf.
+func (r *ExchangeRateRepository) GetLatest(asOf time.Time) ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	err := r.db.Raw(`
+		SELECT DISTINCT ON (base_currency_id, quote_currency_id) *
+		FROM exchange_rates
+		WHERE rate_date <= $1
+		ORDER BY base_currency_id, quote_currency_id, rate_date DESC`, asOf).
+		Scan(&rates).Error
+	return rates, err
+}
+
+func (r *ExchangeRateRepository) CreateImport(rateImport *models.ExchangeRateImport) error {
+	return r.db.Create(rateImport).Error
+}
+
+func (r *ExchangeRateRepository) UpdateImport(rateImport *models.ExchangeRateImport) error {
+	return r.db.Save(rateImport).Error
+}
+
+// GetImports returns the most recent imports, latest first.
+func (r *ExchangeRateRepository) GetImports(limit int) ([]models.ExchangeRateImport, error) {
+	var imports []models.ExchangeRateImport
+	err := r.db.Order("imported_at DESC").
+		Limit(limit).
+		Find(&imports).Error
+	return imports, err
+}
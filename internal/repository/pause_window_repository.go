@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"subscription-tracker/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PauseWindowRepository struct {
+	db *gorm.DB
+}
+
+func NewPauseWindowRepository(db *gorm.DB) *PauseWindowRepository {
+	return &PauseWindowRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *PauseWindowRepository) WithTx(tx *gorm.DB) *PauseWindowRepository {
+	return &PauseWindowRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction.
+func (r *PauseWindowRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+func (r *PauseWindowRepository) Create(pauseWindow *models.PauseWindow) error {
+	return r.db.Create(pauseWindow).Error
+}
+
+func (r *PauseWindowRepository) GetByID(id, subscriptionID, userID models.ULID) (*models.PauseWindow, error) {
+	var pauseWindow models.PauseWindow
+	err := r.db.Where("id = $1 AND subscription_id = $2 AND user_id = $3", id, subscriptionID, userID).
+		First(&pauseWindow).Error
+	if err != nil {
+		return nil, err
+	}
+	return &pauseWindow, nil
+}
+
+// GetAllForSubscription returns the subscription's pause windows, latest
+// first.
+func (r *PauseWindowRepository) GetAllForSubscription(subscriptionID, userID models.ULID) ([]models.PauseWindow, error) {
+	var pauseWindows []models.PauseWindow
+	err := r.db.Where("subscription_id = $1 AND user_id = $2", subscriptionID, userID).
+		Order("start_date DESC").
+		Find(&pauseWindows).Error
+	return pauseWindows, err
+}
+
+// GetAllForUser returns the pause windows of all the user's subscriptions,
+// earliest first.
+func (r *PauseWindowRepository) GetAllForUser(userID models.ULID) ([]models.PauseWindow, error) {
+	var pauseWindows []models.PauseWindow
+	err := r.db.Where("user_id = $1", userID).
+		Order("start_date ASC").
+		Find(&pauseWindows).Error
+	return pauseWindows, err
+}
+
+// GetOpen returns the subscription's scheduled or running pause window.
+func (r *PauseWindowRepository) GetOpen(subscriptionID models.ULID) (*models.PauseWindow, error) {
+	var pauseWindow models.PauseWindow
+	err := r.db.Where("subscription_id = $1 AND resumed_at IS NULL", subscriptionID).
+		Order("start_date DESC").
+		First(&pauseWindow).Error
+	if err != nil {
+		return nil, err
+	}
+	return &pauseWindow, nil
+}
+
+// GetOpenForSubscriptions returns the scheduled and running pause windows
+// of the given subscriptions.
+func (r *PauseWindowRepository) GetOpenForSubscriptions(subscriptionIDs []models.ULID) ([]models.PauseWindow, error) {
+	var pauseWindows []models.PauseWindow
+	err := r.db.Where("subscription_id = ANY($1) AND resumed_at IS NULL", subscriptionIDs).
+		Order("start_date ASC").
+		Find(&pauseWindows).Error
+	return pauseWindows, err
+}
+
+// LockDueToStart locks up to limit scheduled windows whose start date has
+// arrived, skipping rows locked by other workers.
+func (r *PauseWindowRepository) LockDueToStart(now time.Time, limit int) ([]models.PauseWindow, error) {
+	var pauseWindows []models.PauseWindow
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("started_at IS NULL AND resumed_at IS NULL AND start_date <= $1", now).
+		Order("start_date ASC").
+		Limit(limit).
+		Find(&pauseWindows).Error
+	return pauseWindows, err
+}
+
+// LockDueToEnd locks up to limit running windows whose end date has
+// arrived, skipping rows locked by other workers.
+func (r *PauseWindowRepository) LockDueToEnd(now time.Time, limit int) ([]models.PauseWindow, error) {
+	var pauseWindows []models.PauseWindow
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("started_at IS NOT NULL AND resumed_at IS NULL AND end_date <= $1", now).
+		Order("end_date ASC").
+		Limit(limit).
+		Find(&pauseWindows).Error
+	return pauseWindows, err
+}
+
+// CloseAll ends every scheduled or running pause window of the subscription.
+func (r *PauseWindowRepository) CloseAll(subscriptionID models.ULID, at time.Time) error {
+	return r.db.Model(&models.PauseWindow{}).
+		Where("subscription_id = ? AND resumed_at IS NULL", subscriptionID).
+		Update("resumed_at", at).Error
+}
+
+func (r *PauseWindowRepository) Update(pauseWindow *models.PauseWindow) error {
+	return r.db.Save(pauseWindow).Error
+}
+
+func (r *PauseWindowRepository) Delete(pauseWindow *models.PauseWindow) error {
+	return r.db.Delete(pauseWindow).Error
+}
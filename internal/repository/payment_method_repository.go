@@ -4,6 +4,7 @@ import (
 	"subscription-tracker/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PaymentMethodRepository struct {
@@ -14,6 +15,11 @@ func NewPaymentMethodRepository(db *gorm.DB) *PaymentMethodRepository {
 	return &PaymentMethodRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *PaymentMethodRepository) WithTx(tx *gorm.DB) *PaymentMethodRepository {
+	return &PaymentMethodRepository{db: tx}
+}
+
 func (r *PaymentMethodRepository) Create(paymentMethod *models.PaymentMethod) error {
 	return r.db.Create(paymentMethod).Error
 }
@@ -27,9 +33,15 @@ func (r *PaymentMethodRepository) GetByID(id models.ULID) (*models.PaymentMethod
 	return &paymentMethod, nil
 }
 
-func (r *PaymentMethodRepository) GetAllForUser(userID models.ULID) ([]models.PaymentMethod, error) {
+// GetAllForUser returns the user's payment methods, leaving out archived ones
+// unless includeArchived is set.
+func (r *PaymentMethodRepository) GetAllForUser(userID models.ULID, includeArchived bool) ([]models.PaymentMethod, error) {
 	var paymentMethods []models.PaymentMethod
-	err := r.db.Where("user_id = $1", userID).
+	query := r.db.Where("user_id = $1", userID)
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	err := query.
 		Order("name ASC").
 		Find(&paymentMethods).Error
 	if err != nil {
@@ -38,6 +50,30 @@ func (r *PaymentMethodRepository) GetAllForUser(userID models.ULID) ([]models.Pa
 	return paymentMethods, nil
 }
 
+// LockForShare returns the payment method with the given ID, locking it against
+// updates and deletes until the transaction ends.
+func (r *PaymentMethodRepository) LockForShare(id models.ULID) (*models.PaymentMethod, error) {
+	var paymentMethod models.PaymentMethod
+	err := r.db.Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("id = $1", id).
+		First(&paymentMethod).Error
+	if err != nil {
+		return nil, err
+	}
+	return &paymentMethod, nil
+}
+
+// LockByIDs locks the payment methods with the given IDs until the transaction
+// ends, in ID order so that concurrent callers cannot deadlock.
+func (r *PaymentMethodRepository) LockByIDs(ids ...models.ULID) ([]models.PaymentMethod, error) {
+	var paymentMethods []models.PaymentMethod
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ANY($1)", ids).
+		Order("id").
+		Find(&paymentMethods).Error
+	return paymentMethods, err
+}
+
 func (r *PaymentMethodRepository) Update(paymentMethod *models.PaymentMethod) error {
 	return r.db.Save(paymentMethod).Error
 }
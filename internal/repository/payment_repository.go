@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"subscription-tracker/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PaymentRepository struct {
+	db *gorm.DB
+}
+
+// PaymentTotal is the sum of payments made in a single currency.
+type PaymentTotal struct {
+	CurrencyID   models.ULID `json:"currencyId"`
+	CurrencyCode string      `json:"currencyCode"`
+	Total        float64     `json:"total"`
+	Count        int64       `json:"count"`
+}
+
+func NewPaymentRepository(db *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *PaymentRepository) WithTx(tx *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{db: tx}
+}
+
+func (r *PaymentRepository) Create(payment *models.Payment) error {
+	return r.db.Create(payment).Error
+}
+
+func (r *PaymentRepository) GetByID(id, subscriptionID, userID models.ULID) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Where("id = $1 AND subscription_id = $2 AND user_id = $3", id, subscriptionID, userID).
+		First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepository) GetAllForSubscription(subscriptionID, userID models.ULID) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.Where("subscription_id = $1 AND user_id = $2", subscriptionID, userID).
+		Preload("Currency").
+		Preload("PaymentMethod", func(db *gorm.DB) *gorm.DB {
+			// Keep showing payment methods that were removed after the charge
+			return db.Unscoped()
+		}).
+		Order("paid_at DESC").
+		Find(&payments).Error
+	return payments, err
+}
+
+// GetAllForUser returns the payments of all the user's subscriptions,
+// oldest first.
+func (r *PaymentRepository) GetAllForUser(userID models.ULID) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.Where("user_id = $1", userID).
+		Preload("Currency").
+		Order("paid_at ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+// TotalsForSubscription sums the payments of a subscription per currency.
+func (r *PaymentRepository) TotalsForSubscription(subscriptionID, userID models.ULID) ([]PaymentTotal, error) {
+	var totals []PaymentTotal
+	err := r.db.Model(&models.Payment{}).
+		Select("payments.currency_id, currencies.code AS currency_code, SUM(payments.amount) AS total, COUNT(*) AS count").
+		Joins("JOIN currencies ON currencies.id = payments.currency_id").
+		Where("payments.subscription_id = $1 AND payments.user_id = $2", subscriptionID, userID).
+		Group("payments.currency_id, currencies.code").
+		Order("currencies.code ASC").
+		Scan(&totals).Error
+	return totals, err
+}
+
+func (r *PaymentRepository) Update(payment *models.Payment) error {
+	return r.db.Save(payment).Error
+}
+
+func (r *PaymentRepository) Delete(payment *models.Payment) error {
+	return r.db.Delete(payment).Error
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"subscription-tracker/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PriceChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewPriceChangeRepository(db *gorm.DB) *PriceChangeRepository {
+	return &PriceChangeRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *PriceChangeRepository) WithTx(tx *gorm.DB) *PriceChangeRepository {
+	return &PriceChangeRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction.
+func (r *PriceChangeRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+func (r *PriceChangeRepository) Create(priceChange *models.PriceChange) error {
+	return r.db.Create(priceChange).Error
+}
+
+func (r *PriceChangeRepository) GetByID(id, subscriptionID, userID models.ULID) (*models.PriceChange, error) {
+	var priceChange models.PriceChange
+	err := r.db.Where("id = $1 AND subscription_id = $2 AND user_id = $3", id, subscriptionID, userID).
+		First(&priceChange).Error
+	if err != nil {
+		return nil, err
+	}
+	return &priceChange, nil
+}
+
+// GetAllForSubscription returns applied and scheduled changes, latest first.
+func (r *PriceChangeRepository) GetAllForSubscription(subscriptionID, userID models.ULID) ([]models.PriceChange, error) {
+	var priceChanges []models.PriceChange
+	err := r.db.Where("subscription_id = $1 AND user_id = $2", subscriptionID, userID).
+		Preload("Currency").
+		Order("effective_date DESC, created_at DESC").
+		Find(&priceChanges).Error
+	return priceChanges, err
+}
+
+// GetAllForUser returns the applied and scheduled changes of all the user's
+// subscriptions, earliest first.
+func (r *PriceChangeRepository) GetAllForUser(userID models.ULID) ([]models.PriceChange, error) {
+	var priceChanges []models.PriceChange
+	err := r.db.Where("user_id = $1", userID).
+		Preload("Currency").
+		Order("effective_date ASC, created_at ASC").
+		Find(&priceChanges).Error
+	return priceChanges, err
+}
+
+// HasHistory reports whether any change was recorded for the subscription.
+func (r *PriceChangeRepository) HasHistory(subscriptionID models.ULID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.PriceChange{}).Where("subscription_id = $1", subscriptionID).Count(&count).Error
+	return count > 0, err
+}
+
+// GetEffectiveAt returns the latest change effective at or before date,
+// whether or not it has been applied yet.
+func (r *PriceChangeRepository) GetEffectiveAt(subscriptionID models.ULID, date time.Time) (*models.PriceChange, error) {
+	var priceChange models.PriceChange
+	err := r.db.Where("subscription_id = $1 AND effective_date <= $2", subscriptionID, date).
+		Preload("Currency").
+		Order("effective_date DESC, created_at DESC").
+		First(&priceChange).Error
+	if err != nil {
+		return nil, err
+	}
+	return &priceChange, nil
+}
+
+// GetForSubscriptions returns the applied and scheduled changes of the given
+// subscriptions, earliest first.
+func (r *PriceChangeRepository) GetForSubscriptions(subscriptionIDs []models.ULID) ([]models.PriceChange, error) {
+	var priceChanges []models.PriceChange
+	err := r.db.Where("subscription_id = ANY($1)", subscriptionIDs).
+		Preload("Currency").
+		Order("effective_date ASC, created_at ASC").
+		Find(&priceChanges).Error
+	return priceChanges, err
+}
+
+// LockDue locks up to limit scheduled changes whose effective date has
+// arrived, skipping rows locked by other workers.
+func (r *PriceChangeRepository) LockDue(now time.Time, limit int) ([]models.PriceChange, error) {
+	var priceChanges []models.PriceChange
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("applied_at IS NULL AND effective_date <= $1", now).
+		Order("effective_date ASC").
+		Limit(limit).
+		Find(&priceChanges).Error
+	return priceChanges, err
+}
+
+func (r *PriceChangeRepository) MarkApplied(priceChange *models.PriceChange, at time.Time) error {
+	return r.db.Model(priceChange).Update("applied_at", at).Error
+}
+
+func (r *PriceChangeRepository) Delete(priceChange *models.PriceChange) error {
+	return r.db.Delete(priceChange).Error
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"subscription-tracker/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *RefreshTokenRepository) WithTx(tx *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction.
+func (r *RefreshTokenRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+func (r *RefreshTokenRepository) Create(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// LockByHash finds a token by hash and locks it, so two requests presenting
+// the same token are handled one after the other.
+func (r *RefreshTokenRepository) LockByHash(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = $1", hash).
+		First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UpdateFields saves only the named columns of the token.
+func (r *RefreshTokenRepository) UpdateFields(token *models.RefreshToken, fields ...string) error {
+	return r.db.Model(token).Select(fields).Updates(token).Error
+}
+
+// RevokeFamily revokes every active token descending from the same login.
+// Like RevokeAllForUser, the condition uses ? placeholders: GORM numbers the
+// SET values of an UPDATE first, so a literal $1 would refer to revoked_at.
+func (r *RefreshTokenRepository) RevokeFamily(familyID models.ULID, at time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+// RevokeAllForUser revokes every active token of the user.
+func (r *RefreshTokenRepository) RevokeAllForUser(userID models.ULID, at time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+// DeleteExpired removes tokens that expired before cutoff and returns how
+// many were removed.
+func (r *RefreshTokenRepository) DeleteExpired(cutoff time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= $1", cutoff).Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
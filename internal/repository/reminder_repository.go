@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"subscription-tracker/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReminderRepository struct {
+	db *gorm.DB
+}
+
+func NewReminderRepository(db *gorm.DB) *ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *ReminderRepository) WithTx(tx *gorm.DB) *ReminderRepository {
+	return &ReminderRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction.
+func (r *ReminderRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+// CreateIfAbsent inserts the reminder unless one already exists for the same
+// occurrence. It reports whether a row was inserted.
+func (r *ReminderRepository) CreateIfAbsent(reminder *models.Reminder) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(reminder)
+	return result.RowsAffected > 0, result.Error
+}
+
+// LockPending locks up to limit unsent reminders whose billing date is still
+// ahead and whose retry delay has passed, skipping rows another worker is
+// already delivering.
+func (r *ReminderRepository) LockPending(now time.Time, maxAttempts, limit int) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("sent_at IS NULL AND attempts < $1 AND remind_at <= $2 AND billing_date > $3 AND (next_attempt_at IS NULL OR next_attempt_at <= $4)",
+			maxAttempts, now, now, now).
+		Order("remind_at ASC").
+		Limit(limit).
+		Find(&reminders).Error
+	return reminders, err
+}
+
+func (r *ReminderRepository) Update(reminder *models.Reminder) error {
+	return r.db.Save(reminder).Error
+}
+
+// GetAllForUser returns the reminders of all the user's subscriptions,
+// sent and pending, earliest first.
+func (r *ReminderRepository) GetAllForUser(userID models.ULID) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	err := r.db.Where("user_id = $1", userID).
+		Order("remind_at ASC").
+		Find(&reminders).Error
+	return reminders, err
+}
@@ -2,8 +2,10 @@ package repository
 
 import (
 	"subscription-tracker/internal/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SubscriptionRepository struct {
@@ -14,13 +16,29 @@ func NewSubscriptionRepository(db *gorm.DB) *SubscriptionRepository {
 	return &SubscriptionRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *SubscriptionRepository) WithTx(tx *gorm.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction.
+func (r *SubscriptionRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
 func (r *SubscriptionRepository) Create(subscription *models.Subscription) error {
 	return r.db.Create(subscription).Error
 }
 
-func (r *SubscriptionRepository) GetAll(userID models.ULID) ([]models.Subscription, error) {
+// GetAll returns the user's subscriptions, limited to the given statuses
+// when any are passed.
+func (r *SubscriptionRepository) GetAll(userID models.ULID, statuses ...models.SubscriptionStatus) ([]models.Subscription, error) {
 	var subscriptions []models.Subscription
-	err := r.db.Where("user_id = $1", userID).
+	query := r.db.Where("user_id = $1", userID)
+	if len(statuses) > 0 {
+		query = query.Where("status = ANY($2)", statuses)
+	}
+	err := query.
 		Preload("Category").
 		Preload("Currency").
 		Preload("BillingCycle").
@@ -29,6 +47,46 @@ func (r *SubscriptionRepository) GetAll(userID models.ULID) ([]models.Subscripti
 	return subscriptions, err
 }
 
+// GetAllWithDeleted returns all of the user's subscriptions, including
+// deleted ones, with references that were deleted since still loaded.
+func (r *SubscriptionRepository) GetAllWithDeleted(userID models.ULID) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	unscoped := func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}
+	err := r.db.Unscoped().
+		Where("user_id = $1", userID).
+		Preload("Category", unscoped).
+		Preload("Currency").
+		Preload("BillingCycle", unscoped).
+		Preload("PaymentMethod", unscoped).
+		Order("created_at ASC").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetActiveWithDetails returns the user's live subscriptions (see
+// models.LiveStatuses) with every reference loaded, for projecting future
+// billing dates.
+func (r *SubscriptionRepository) GetActiveWithDetails(userID models.ULID) ([]models.Subscription, error) {
+	return r.GetWithDetails(userID, models.LiveStatuses)
+}
+
+// GetWithDetails returns the user's subscriptions in the given statuses with
+// every reference loaded.
+func (r *SubscriptionRepository) GetWithDetails(userID models.ULID, statuses []models.SubscriptionStatus) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.db.Where("user_id = $1 AND status = ANY($2)", userID, statuses).
+		Preload("Category").
+		Preload("Currency").
+		Preload("BillingCycle", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Preload("PaymentMethod").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
 func (r *SubscriptionRepository) GetByID(id, userID models.ULID) (*models.Subscription, error) {
 	var subscription models.Subscription
 	err := r.db.Where("id = $1 AND user_id = $2", id, userID).
@@ -60,6 +118,35 @@ func (r *SubscriptionRepository) GetByPaymentMethod(paymentMethodID, userID mode
 	return subscriptions, err
 }
 
+// ReassignPaymentMethod moves the user's subscriptions from one payment method
+// to another, limited to subscriptionIDs if any are given. It returns the
+// number of subscriptions moved.
+func (r *SubscriptionRepository) ReassignPaymentMethod(userID, fromID, toID models.ULID, subscriptionIDs []models.ULID) (int64, error) {
+	return r.reassign("payment_method_id", userID, fromID, toID, subscriptionIDs)
+}
+
+// ReassignCategory moves the user's subscriptions from one category to
+// another.
+func (r *SubscriptionRepository) ReassignCategory(userID, fromID, toID models.ULID) (int64, error) {
+	return r.reassign("category_id", userID, fromID, toID, nil)
+}
+
+// ReassignBillingCycle moves the user's subscriptions from one billing cycle
+// to another.
+func (r *SubscriptionRepository) ReassignBillingCycle(userID, fromID, toID models.ULID) (int64, error) {
+	return r.reassign("billing_cycle_id", userID, fromID, toID, nil)
+}
+
+func (r *SubscriptionRepository) reassign(column string, userID, fromID, toID models.ULID, subscriptionIDs []models.ULID) (int64, error) {
+	query := r.db.Model(&models.Subscription{}).
+		Where("user_id = ? AND "+column+" = ?", userID, fromID)
+	if len(subscriptionIDs) > 0 {
+		query = query.Where("id IN ?", subscriptionIDs)
+	}
+	result := query.Update(column, toID)
+	return result.RowsAffected, result.Error
+}
+
 func (r *SubscriptionRepository) Update(subscription *models.Subscription) error {
 	return r.db.Save(subscription).Error
 }
@@ -67,3 +154,101 @@ func (r *SubscriptionRepository) Update(subscription *models.Subscription) error
 func (r *SubscriptionRepository) Delete(subscription *models.Subscription) error {
 	return r.db.Delete(subscription).Error
 }
+
+// LockDueForRenewal locks up to limit renewable subscriptions whose next billing
+// date is at or before now and before any pending cancellation. Rows locked by another transaction are skipped so
+// several workers can renew concurrently without processing the same row.
+func (r *SubscriptionRepository) LockDueForRenewal(now time.Time, limit int) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ANY($1) AND next_billing_date <= $2", models.RenewableStatuses, now).
+		Where("cancels_at IS NULL OR next_billing_date < cancels_at").
+		Order("next_billing_date ASC").
+		Limit(limit).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// LockEndedTrials locks up to limit trialing subscriptions whose free trial
+// has ended, skipping rows locked by other workers.
+func (r *SubscriptionRepository) LockEndedTrials(now time.Time, limit int) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = $1 AND trial_end_date <= $2", models.SubscriptionStatusTrialing, now).
+		Order("trial_end_date ASC").
+		Limit(limit).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// LockDueCancellations locks up to limit subscriptions pending cancellation
+// whose cancellation has taken effect, skipping rows locked by other workers.
+func (r *SubscriptionRepository) LockDueCancellations(now time.Time, limit int) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = $1 AND cancels_at <= $2", models.SubscriptionStatusPendingCancellation, now).
+		Order("cancels_at ASC").
+		Limit(limit).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetTrialsEndingBefore returns the user's trials ending at or before until,
+// soonest first.
+func (r *SubscriptionRepository) GetTrialsEndingBefore(userID models.ULID, until time.Time) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.db.Where("user_id = $1 AND status = $2 AND trial_end_date <= $3", userID, models.SubscriptionStatusTrialing, until).
+		Preload("Category").
+		Preload("Currency").
+		Preload("BillingCycle").
+		Preload("PaymentMethod").
+		Order("trial_end_date ASC").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// UpdateFields saves only the named columns of the subscription.
+func (r *SubscriptionRepository) UpdateFields(subscription *models.Subscription, fields ...string) error {
+	return r.db.Model(subscription).Select(fields).Updates(subscription).Error
+}
+
+// UpdateNextBillingDate saves the next billing date together with the anchor
+// day it was computed from.
+func (r *SubscriptionRepository) UpdateNextBillingDate(subscription *models.Subscription) error {
+	subscription.BillingAnchorDay = subscription.AnchorDay()
+	return r.UpdateFields(subscription, "next_billing_date", "billing_anchor_day")
+}
+
+// GetDueForReminder returns live subscriptions whose reminder window
+// (NextBillingDate minus ReminderDays) has opened and that have no reminder
+// for their current billing date yet. Billing dates on or after a pending
+// cancellation, and accounts awaiting deletion, are skipped.
+func (r *SubscriptionRepository) GetDueForReminder(now time.Time) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.db.Where("status = ANY($1) AND reminder_days > 0", models.LiveStatuses).
+		Where("next_billing_date > $2", now).
+		Where("cancels_at IS NULL OR next_billing_date < cancels_at").
+		Where("next_billing_date - reminder_days * INTERVAL '1 day' <= $3", now).
+		Where("NOT EXISTS (SELECT 1 FROM reminders WHERE reminders.subscription_id = subscriptions.id AND reminders.billing_date = subscriptions.next_billing_date)").
+		Where("NOT EXISTS (SELECT 1 FROM users WHERE users.id = subscriptions.user_id AND users.deletes_at IS NOT NULL)").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetByIDWithDetails loads a subscription with its references regardless of
+// which user owns it. Intended for background jobs.
+func (r *SubscriptionRepository) GetByIDWithDetails(id models.ULID) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := r.db.Where("id = $1", id).
+		Preload("Category").
+		Preload("Currency").
+		Preload("BillingCycle", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Preload("PaymentMethod").
+		First(&subscription).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"strings"
 	"subscription-tracker/internal/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
@@ -15,10 +17,29 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction.
+func (r *UserRepository) WithTx(tx *gorm.DB) *UserRepository {
+	return &UserRepository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction.
+func (r *UserRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
 func (r *UserRepository) Create(user *models.User) error {
 	return r.db.Create(user).Error
 }
 
+func (r *UserRepository) GetByID(id models.ULID) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("id = $1", id).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	var user models.User
 	err := r.db.Where("email = $1", strings.ToLower(email)).First(&user).Error
@@ -36,3 +57,67 @@ func (r *UserRepository) EmailExists(email string) (bool, error) {
 	}
 	return count > 0, nil
 }
+
+// UpdateFields saves only the named columns of the user.
+func (r *UserRepository) UpdateFields(user *models.User, fields ...string) error {
+	return r.db.Model(user).Select(fields).Updates(user).Error
+}
+
+// RevokeTokens bumps the user's token version, so access tokens issued
+// before stop working, and loads the new version into user. The increment
+// happens in the database so concurrent revocations cannot reuse a version.
+func (r *UserRepository) RevokeTokens(user *models.User, at time.Time) error {
+	return r.db.Model(user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "token_version"}, {Name: "tokens_revoked_at"}}}).
+		Updates(map[string]interface{}{
+			"token_version":     gorm.Expr("token_version + 1"),
+			"tokens_revoked_at": at,
+		}).Error
+}
+
+// LockDueForDeletion locks up to limit accounts whose deletion grace period
+// has ended. Locked rows are skipped so several workers can purge at once.
+func (r *UserRepository) LockDueForDeletion(now time.Time, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("deletes_at <= $1", now).
+		Order("deletes_at ASC").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// Purge permanently deletes the user and every row they own, bypassing soft
+// deletes. Rows are removed before the rows they reference. Exchange rate
+// imports are kept for the audit trail without the importer.
+func (r *UserRepository) Purge(userID models.ULID) error {
+	owned := []interface{}{
+		&models.Reminder{},
+		&models.Payment{},
+		&models.PriceChange{},
+		&models.Cancellation{},
+		&models.PauseWindow{},
+		&models.Budget{},
+		&models.CalendarToken{},
+		&models.RefreshToken{},
+		&models.DataExport{},
+		&models.Subscription{},
+		&models.PaymentMethod{},
+		&models.Category{},
+		&models.BillingCycle{},
+	}
+	for _, model := range owned {
+		if err := r.db.Unscoped().Where("user_id = $1", userID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	err := r.db.Model(&models.ExchangeRateImport{}).
+		Where("imported_by_id = ?", userID).
+		Update("imported_by_id", nil).Error
+	if err != nil {
+		return err
+	}
+
+	return r.db.Unscoped().Where("id = $1", userID).Delete(&models.User{}).Error
+}
@@ -15,20 +15,91 @@ func (s *Server) setupRoutes() {
 	billingCycleRepo := repository.NewBillingCycleRepository(s.db)
 	subscriptionRepo := repository.NewSubscriptionRepository(s.db)
 	paymentMethodRepo := repository.NewPaymentMethodRepository(s.db)
+	paymentRepo := repository.NewPaymentRepository(s.db)
+	calendarTokenRepo := repository.NewCalendarTokenRepository(s.db)
+	priceChangeRepo := repository.NewPriceChangeRepository(s.db)
+	cancellationRepo := repository.NewCancellationRepository(s.db)
+	pauseWindowRepo := repository.NewPauseWindowRepository(s.db)
+	reminderRepo := repository.NewReminderRepository(s.db)
+	analyticsRepo := repository.NewAnalyticsRepository(s.db)
+	exchangeRateRepo := repository.NewExchangeRateRepository(s.db)
+	budgetRepo := repository.NewBudgetRepository(s.db)
+	dataExportRepo := repository.NewDataExportRepository(s.db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(s.db)
 
 	// Initialize services with config
-	authService := services.NewAuthService(userRepo, s.config)
-	categoryService := services.NewCategoryService(categoryRepo)
+	authService := services.NewAuthService(userRepo, refreshTokenRepo, s.config)
+	categoryService := services.NewCategoryService(categoryRepo, subscriptionRepo, budgetRepo)
 	currencyService := services.NewCurrencyService(currencyRepo)
-	billingCycleService := services.NewBillingCycleService(billingCycleRepo)
+	billingCycleService := services.NewBillingCycleService(billingCycleRepo, subscriptionRepo)
 	subscriptionService := services.NewSubscriptionService(
 		subscriptionRepo,
 		categoryRepo,
 		currencyRepo,
 		billingCycleRepo,
 		paymentMethodRepo,
+		priceChangeRepo,
+		cancellationRepo,
+		pauseWindowRepo,
+		userRepo,
+		exchangeRateRepo,
+		budgetRepo,
+		analyticsRepo,
 	)
-	paymentMethodService := services.NewPaymentMethodService(paymentMethodRepo)
+	paymentMethodService := services.NewPaymentMethodService(paymentMethodRepo, subscriptionRepo)
+	paymentService := services.NewPaymentService(
+		paymentRepo,
+		subscriptionRepo,
+		currencyRepo,
+		paymentMethodRepo,
+	)
+	calendarService := services.NewCalendarService(calendarTokenRepo, subscriptionRepo)
+	trialService := services.NewTrialService(subscriptionRepo, billingCycleRepo, paymentRepo, priceChangeRepo)
+	priceService := services.NewPriceService(priceChangeRepo, subscriptionRepo, currencyRepo)
+	cancellationService := services.NewCancellationService(cancellationRepo, subscriptionRepo, billingCycleRepo)
+	analyticsService := services.NewAnalyticsService(analyticsRepo)
+	exchangeRateService := services.NewExchangeRateService(exchangeRateRepo, currencyRepo)
+	userService := services.NewUserService(userRepo, currencyRepo, refreshTokenRepo, s.config)
+	statementService := services.NewStatementService(
+		subscriptionRepo,
+		categoryRepo,
+		currencyRepo,
+		billingCycleRepo,
+		paymentMethodRepo,
+		priceChangeRepo,
+	)
+	transferService := services.NewSubscriptionTransferService(
+		subscriptionRepo,
+		categoryRepo,
+		currencyRepo,
+		billingCycleRepo,
+		paymentMethodRepo,
+		priceChangeRepo,
+	)
+	dataExportService := services.NewDataExportService(
+		dataExportRepo,
+		userRepo,
+		currencyRepo,
+		subscriptionRepo,
+		paymentMethodRepo,
+		categoryRepo,
+		billingCycleRepo,
+		paymentRepo,
+		priceChangeRepo,
+		reminderRepo,
+		budgetRepo,
+		pauseWindowRepo,
+		cancellationRepo,
+	)
+	forecastService := services.NewForecastService(
+		subscriptionRepo,
+		pauseWindowRepo,
+		userRepo,
+		currencyRepo,
+		exchangeRateRepo,
+		priceService,
+	)
+	budgetService := services.NewBudgetService(budgetRepo, categoryRepo, currencyRepo, analyticsRepo, exchangeRateRepo)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
@@ -37,18 +108,36 @@ func (s *Server) setupRoutes() {
 	billingCycleHandler := handlers.NewBillingCycleHandler(billingCycleService)
 	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
 	paymentMethodHandler := handlers.NewPaymentMethodHandler(paymentMethodService)
+	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+	trialHandler := handlers.NewTrialHandler(trialService)
+	priceHandler := handlers.NewPriceHandler(priceService)
+	cancellationHandler := handlers.NewCancellationHandler(cancellationService)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(exchangeRateService)
+	userHandler := handlers.NewUserHandler(userService)
+	statementHandler := handlers.NewStatementHandler(statementService)
+	transferHandler := handlers.NewSubscriptionTransferHandler(transferService)
+	dataExportHandler := handlers.NewDataExportHandler(dataExportService)
+	forecastHandler := handlers.NewForecastHandler(forecastService)
+	budgetHandler := handlers.NewBudgetHandler(budgetService)
 
 	// Public routes
 	public := s.router.Group("/api/v1")
 	{
 		public.POST("/auth/register", authHandler.Register)
 		public.POST("/auth/login", authHandler.Login)
+		// Authenticated by the refresh token, so they work once the access token has expired
+		public.POST("/auth/refresh", authHandler.Refresh)
+		public.POST("/auth/logout", authHandler.Logout)
 		public.GET("/currencies", currencyHandler.GetAll)
+		// Calendar apps can't send Bearer tokens; the feed URL carries its own secret
+		public.GET("/calendar/feed/:token", calendarHandler.Feed)
 	}
 
 	// Protected routes
 	protected := s.router.Group("/api/v1")
-	protected.Use(middleware.AuthMiddleware(s.config))
+	protected.Use(middleware.AuthMiddleware(s.config, userRepo))
 	{
 		// Category routes
 		categories := protected.Group("/categories")
@@ -73,21 +162,102 @@ func (s *Server) setupRoutes() {
 		{
 			paymentMethods.POST("/", paymentMethodHandler.Create)
 			paymentMethods.GET("/", paymentMethodHandler.GetAll)
+			paymentMethods.GET("/at-risk", paymentMethodHandler.AtRisk)
 			paymentMethods.PUT("/:id", paymentMethodHandler.Update)
+			paymentMethods.POST("/:id/migrate", paymentMethodHandler.Migrate)
 			paymentMethods.DELETE("/:id", paymentMethodHandler.Delete)
 		}
 
+		// Budget routes
+		budgets := protected.Group("/budgets")
+		{
+			budgets.GET("/", budgetHandler.GetAll)
+			budgets.POST("/", budgetHandler.Create)
+			budgets.GET("/:id", budgetHandler.GetByID)
+			budgets.PUT("/:id", budgetHandler.Update)
+			budgets.DELETE("/:id", budgetHandler.Delete)
+		}
+
+		// Current user routes
+		me := protected.Group("/me")
+		{
+			me.GET("", userHandler.GetProfile)
+			me.PUT("", userHandler.UpdateProfile)
+			me.DELETE("", userHandler.DeleteAccount)
+			me.PUT("/password", userHandler.ChangePassword)
+			me.POST("/logout-all", authHandler.LogoutAll)
+			me.PUT("/base-currency", userHandler.SetBaseCurrency)
+			me.POST("/export", dataExportHandler.Request)
+			me.GET("/export", dataExportHandler.Status)
+			me.GET("/export/download", dataExportHandler.Download)
+		}
+
+		protected.GET("/exchange-rates", exchangeRateHandler.GetLatest)
+
+		// Analytics routes
+		analytics := protected.Group("/analytics")
+		{
+			analytics.GET("/spend", analyticsHandler.Spend)
+		}
+
+		protected.GET("/forecast", forecastHandler.Forecast)
+
+		// Bank statement import, proposing draft subscriptions
+		protected.POST("/statements/import", statementHandler.Import)
+
+		// Calendar feed token routes
+		calendar := protected.Group("/calendar")
+		{
+			calendar.POST("/token", calendarHandler.CreateToken)
+			calendar.DELETE("/token", calendarHandler.RevokeToken)
+		}
+
 		// Subscription routes
 		subscriptions := protected.Group("/subscriptions")
 		{
 			subscriptions.POST("/", subscriptionHandler.Create)
 			subscriptions.GET("/", subscriptionHandler.GetAll)
+			subscriptions.GET("/upcoming", subscriptionHandler.Upcoming)
+			subscriptions.GET("/trials", trialHandler.EndingSoon)
+			subscriptions.GET("/savings", cancellationHandler.AllSavings)
+			subscriptions.GET("/export", transferHandler.Export)
+			subscriptions.POST("/import", transferHandler.Import)
 			subscriptions.GET("/:id", subscriptionHandler.GetByID)
 			subscriptions.GET("/category/:categoryId", subscriptionHandler.GetByCategory)
 			subscriptions.GET("/billing-cycle/:billingCycleId", subscriptionHandler.GetByBillingCycle)
 			subscriptions.GET("/payment-method/:paymentMethodId", subscriptionHandler.GetByPaymentMethod)
 			subscriptions.PUT("/:id", subscriptionHandler.Update)
 			subscriptions.DELETE("/:id", subscriptionHandler.Delete)
+
+			// Lifecycle transitions
+			subscriptions.POST("/:id/confirm", subscriptionHandler.Confirm)
+			subscriptions.POST("/:id/pause", subscriptionHandler.Pause)
+			subscriptions.POST("/:id/resume", subscriptionHandler.Resume)
+			subscriptions.GET("/:id/pauses", subscriptionHandler.GetPauses)
+			subscriptions.DELETE("/:id/pauses/:pauseId", subscriptionHandler.CancelPause)
+			subscriptions.POST("/:id/cancel", cancellationHandler.Cancel)
+			subscriptions.GET("/:id/cancellation", cancellationHandler.Savings)
+
+			// Payment history of a subscription
+			subscriptions.GET("/:id/payments", paymentHandler.GetAll)
+			subscriptions.POST("/:id/payments", paymentHandler.Create)
+			subscriptions.PUT("/:id/payments/:paymentId", paymentHandler.Update)
+			subscriptions.DELETE("/:id/payments/:paymentId", paymentHandler.Delete)
+
+			// Price history and scheduled price changes
+			subscriptions.GET("/:id/prices", priceHandler.GetHistory)
+			subscriptions.GET("/:id/prices/effective", priceHandler.GetEffective)
+			subscriptions.POST("/:id/prices", priceHandler.Schedule)
+			subscriptions.DELETE("/:id/prices/:priceId", priceHandler.CancelScheduled)
 		}
 	}
+
+	// Admin routes
+	admin := s.router.Group("/api/v1/admin")
+	admin.Use(middleware.AuthMiddleware(s.config, userRepo), middleware.AdminMiddleware(userRepo))
+	{
+		admin.POST("/exchange-rates", exchangeRateHandler.Set)
+		admin.POST("/exchange-rates/import", exchangeRateHandler.Import)
+		admin.GET("/exchange-rates/imports", exchangeRateHandler.GetImports)
+	}
 }
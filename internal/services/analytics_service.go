@@ -0,0 +1,49 @@
+package services
+
+import (
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+)
+
+type AnalyticsService struct {
+	analyticsRepo *repository.AnalyticsRepository
+}
+
+// SpendReport breaks down the normalized cost of live subscriptions. Amounts
+// in different currencies are never added up, so every group is split per
+// currency.
+type SpendReport struct {
+	ByCurrency      []repository.SpendTotal `json:"byCurrency"`
+	ByCategory      []repository.SpendTotal `json:"byCategory"`
+	ByPaymentMethod []repository.SpendTotal `json:"byPaymentMethod"`
+}
+
+func NewAnalyticsService(analyticsRepo *repository.AnalyticsRepository) *AnalyticsService {
+	return &AnalyticsService{
+		analyticsRepo: analyticsRepo,
+	}
+}
+
+// Spend normalizes each live subscription to its monthly and yearly cost
+// using its billing cycle and totals them per currency, category and payment
+// method.
+func (s *AnalyticsService) Spend(userID models.ULID) (*SpendReport, error) {
+	byCurrency, err := s.analyticsRepo.SpendByCurrency(userID)
+	if err != nil {
+		return nil, err
+	}
+	byCategory, err := s.analyticsRepo.SpendByCategory(userID)
+	if err != nil {
+		return nil, err
+	}
+	byPaymentMethod, err := s.analyticsRepo.SpendByPaymentMethod(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpendReport{
+		ByCurrency:      byCurrency,
+		ByCategory:      byCategory,
+		ByPaymentMethod: byPaymentMethod,
+	}, nil
+}
@@ -1,21 +1,29 @@
 package services
 
 import (
+	"fmt"
 	"subscription-tracker/internal/auth"
 	"subscription-tracker/internal/config"
 	"subscription-tracker/internal/models"
 	"subscription-tracker/internal/repository"
 	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type AuthService struct {
-	userRepo *repository.UserRepository
-	config   *config.Config
+	userRepo         *repository.UserRepository
+	refreshTokenRepo *repository.RefreshTokenRepository
+	config           *config.Config
 }
 
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+	// Cancels a pending account deletion. Accounts awaiting deletion
+	// cannot log in without it.
+	RestoreAccount bool `json:"restoreAccount"`
 }
 
 type RegisterRequest struct {
@@ -24,15 +32,32 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// AuthResponse carries a short-lived access token, sent as the Bearer
+// token, and the refresh token that obtains the next one.
 type AuthResponse struct {
-	Token string       `json:"token"`
-	User  *models.User `json:"user"`
+	Token        string       `json:"token"`
+	ExpiresAt    time.Time    `json:"expiresAt"` // When Token expires
+	RefreshToken string       `json:"refreshToken"`
+	User         *UserProfile `json:"user"`
 }
 
-func NewAuthService(userRepo *repository.UserRepository, cfg *config.Config) *AuthService {
+func NewAuthService(
+	userRepo *repository.UserRepository,
+	refreshTokenRepo *repository.RefreshTokenRepository,
+	cfg *config.Config,
+) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		config:   cfg,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		config:           cfg,
 	}
 }
 
@@ -46,15 +71,19 @@ func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
 		return nil, utils.NewValidationError("credentials", "invalid credentials")
 	}
 
-	token, err := auth.GenerateToken(user, s.config)
-	if err != nil {
-		return nil, utils.NewInternalError("failed to generate token")
+	if user.DeletesAt != nil {
+		if !req.RestoreAccount {
+			return nil, utils.NewForbiddenError(fmt.Sprintf(
+				"account is scheduled for deletion on %s; log in with restoreAccount to cancel the deletion",
+				user.DeletesAt.Format(time.DateOnly)))
+		}
+		user.DeletesAt = nil
+		if err := s.userRepo.UpdateFields(user, "deletes_at"); err != nil {
+			return nil, err
+		}
 	}
 
-	return &AuthResponse{
-		Token: token,
-		User:  user,
-	}, nil
+	return issueTokens(s.refreshTokenRepo, s.config, user, nil)
 }
 
 func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
@@ -75,19 +104,165 @@ func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 		Name:         req.Name,
 		Email:        req.Email,
 		PasswordHash: hashedPassword,
+		IsAdmin:      s.config.Account.IsAdminEmail(req.Email),
 	}
 
 	if err := s.userRepo.Create(user); err != nil {
 		return nil, err
 	}
 
-	token, err := auth.GenerateToken(user, s.config)
+	return issueTokens(s.refreshTokenRepo, s.config, user, nil)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new
+// refresh token of the same family; the presented token cannot be used
+// again. Presenting a token that was already exchanged means it has leaked,
+// so its whole family is revoked and the user has to log in again.
+func (s *AuthService) Refresh(req *RefreshRequest) (*AuthResponse, error) {
+	var response *AuthResponse
+	reused := false
+
+	err := s.refreshTokenRepo.Transaction(func(tx *gorm.DB) error {
+		refreshTokenRepo := s.refreshTokenRepo.WithTx(tx)
+
+		token, err := refreshTokenRepo.LockByHash(auth.HashOpaqueToken(req.RefreshToken))
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return utils.NewUnauthorizedError("invalid refresh token")
+			}
+			return err
+		}
+
+		now := time.Now()
+		if token.RevokedAt != nil || !token.ExpiresAt.After(now) {
+			return utils.NewUnauthorizedError("invalid refresh token")
+		}
+		if token.RotatedAt != nil {
+			reused = true
+			return refreshTokenRepo.RevokeFamily(token.FamilyID, now)
+		}
+
+		user, err := s.userRepo.GetByID(token.UserID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return utils.NewUnauthorizedError("invalid refresh token")
+			}
+			return err
+		}
+		if user.DeletesAt != nil {
+			return utils.NewUnauthorizedError("account is scheduled for deletion")
+		}
+		if user.TokensRevokedAt != nil && token.CreatedAt.Before(*user.TokensRevokedAt) {
+			return utils.NewUnauthorizedError("invalid refresh token")
+		}
+
+		token.RotatedAt = &now
+		if err := refreshTokenRepo.UpdateFields(token, "rotated_at"); err != nil {
+			return err
+		}
+
+		response, err = issueTokens(refreshTokenRepo, s.config, user, &token.FamilyID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, utils.NewUnauthorizedError("refresh token was already used; log in again")
+	}
+
+	return response, nil
+}
+
+// Logout revokes the refresh token's family, ending the session it belongs
+// to. Access tokens already issued stay valid until they expire. Like
+// Refresh, it only accepts a token that is still usable, and treats one that
+// was already exchanged as leaked.
+func (s *AuthService) Logout(req *LogoutRequest) error {
+	reused := false
+
+	err := s.refreshTokenRepo.Transaction(func(tx *gorm.DB) error {
+		refreshTokenRepo := s.refreshTokenRepo.WithTx(tx)
+
+		token, err := refreshTokenRepo.LockByHash(auth.HashOpaqueToken(req.RefreshToken))
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return utils.NewUnauthorizedError("invalid refresh token")
+			}
+			return err
+		}
+
+		now := time.Now()
+		if token.RevokedAt != nil || !token.ExpiresAt.After(now) {
+			return utils.NewUnauthorizedError("invalid refresh token")
+		}
+		if token.RotatedAt != nil {
+			reused = true
+		}
+		return refreshTokenRepo.RevokeFamily(token.FamilyID, now)
+	})
+	if err != nil {
+		return err
+	}
+	if reused {
+		return utils.NewUnauthorizedError("refresh token was already used; log in again")
+	}
+	return nil
+}
+
+// LogoutAll ends every session of the user: all refresh tokens are revoked
+// and access tokens that haven't expired yet stop working.
+func (s *AuthService) LogoutAll(userID models.ULID) error {
+	return s.userRepo.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		user := &models.User{ID: userID}
+		if err := s.userRepo.WithTx(tx).RevokeTokens(user, now); err != nil {
+			return err
+		}
+		return s.refreshTokenRepo.WithTx(tx).RevokeAllForUser(userID, now)
+	})
+}
+
+// DeleteExpiredRefreshTokens removes refresh tokens that can no longer be
+// used. It returns the number of tokens removed.
+func (s *AuthService) DeleteExpiredRefreshTokens(now time.Time) (int64, error) {
+	return s.refreshTokenRepo.DeleteExpired(now)
+}
+
+// issueTokens signs an access token for user and creates a refresh token in
+// familyID, or in a new family when familyID is nil.
+func issueTokens(
+	refreshTokenRepo *repository.RefreshTokenRepository,
+	cfg *config.Config,
+	user *models.User,
+	familyID *models.ULID,
+) (*AuthResponse, error) {
+	accessToken, expiresAt, err := auth.GenerateToken(user, cfg)
 	if err != nil {
+		return nil, utils.NewInternalError("failed to generate token")
+	}
+
+	refreshToken, hash, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	token := &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  models.NewULID(),
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(cfg.JWT.RefreshTokenTTL),
+	}
+	if familyID != nil {
+		token.FamilyID = *familyID
+	}
+	if err := refreshTokenRepo.Create(token); err != nil {
 		return nil, err
 	}
 
 	return &AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        accessToken,
+		ExpiresAt:    expiresAt,
+		RefreshToken: refreshToken,
+		User:         newUserProfile(user),
 	}, nil
 }
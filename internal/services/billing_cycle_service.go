@@ -11,25 +11,36 @@ import (
 
 type BillingCycleService struct {
 	billingCycleRepo *repository.BillingCycleRepository
+	subscriptionRepo *repository.SubscriptionRepository
 }
 
 type CreateBillingCycleRequest struct {
-	Name string `json:"name" binding:"required"`
-	Days int    `json:"days" binding:"required,min=1"`
+	Name     string                  `json:"name" binding:"required"`
+	Interval int                     `json:"interval" binding:"required,min=1"`
+	Unit     models.BillingCycleUnit `json:"unit" binding:"required"`
 }
 
 type UpdateBillingCycleRequest struct {
-	Name string `json:"name" binding:"required"`
-	Days int    `json:"days" binding:"required,min=1"`
+	Name     string                  `json:"name" binding:"required"`
+	Interval int                     `json:"interval" binding:"required,min=1"`
+	Unit     models.BillingCycleUnit `json:"unit" binding:"required"`
 }
 
-func NewBillingCycleService(billingCycleRepo *repository.BillingCycleRepository) *BillingCycleService {
+func NewBillingCycleService(
+	billingCycleRepo *repository.BillingCycleRepository,
+	subscriptionRepo *repository.SubscriptionRepository,
+) *BillingCycleService {
 	return &BillingCycleService{
 		billingCycleRepo: billingCycleRepo,
+		subscriptionRepo: subscriptionRepo,
 	}
 }
 
 func (s *BillingCycleService) Create(req *CreateBillingCycleRequest, userID models.ULID) (*models.BillingCycle, error) {
+	if !models.IsValidBillingCycleUnit(req.Unit) {
+		return nil, utils.NewValidationError("unit", "unit must be one of day, week, month or year")
+	}
+
 	exists, err := s.billingCycleRepo.ExistsByNameAndUser(req.Name, userID, nil)
 	if err != nil {
 		return nil, err
@@ -40,7 +51,8 @@ func (s *BillingCycleService) Create(req *CreateBillingCycleRequest, userID mode
 
 	billingCycle := &models.BillingCycle{
 		Name:          req.Name,
-		Days:          req.Days,
+		Interval:      req.Interval,
+		Unit:          req.Unit,
 		UserID:        &userID,
 		SystemDefined: false,
 	}
@@ -73,6 +85,10 @@ func (s *BillingCycleService) GetByID(id, userID models.ULID) (*models.BillingCy
 }
 
 func (s *BillingCycleService) Update(id models.ULID, req *UpdateBillingCycleRequest, userID models.ULID) (*models.BillingCycle, error) {
+	if !models.IsValidBillingCycleUnit(req.Unit) {
+		return nil, utils.NewValidationError("unit", "unit must be one of day, week, month or year")
+	}
+
 	billingCycle, err := s.GetByID(id, userID)
 	if err != nil {
 		return nil, err
@@ -91,7 +107,8 @@ func (s *BillingCycleService) Update(id models.ULID, req *UpdateBillingCycleRequ
 	}
 
 	billingCycle.Name = req.Name
-	billingCycle.Days = req.Days
+	billingCycle.Interval = req.Interval
+	billingCycle.Unit = req.Unit
 	if err := s.billingCycleRepo.Update(billingCycle); err != nil {
 		return nil, err
 	}
@@ -99,19 +116,57 @@ func (s *BillingCycleService) Update(id models.ULID, req *UpdateBillingCycleRequ
 	return billingCycle, nil
 }
 
-func (s *BillingCycleService) Delete(id models.ULID, userID models.ULID) error {
+// Delete removes a billing cycle. A billing cycle still used by
+// subscriptions is only deleted when reassignTo names another billing cycle
+// to move them to; otherwise a conflict listing them is returned.
+func (s *BillingCycleService) Delete(id models.ULID, userID models.ULID, reassignTo *models.ULID) error {
 	billingCycle, err := s.billingCycleRepo.GetByID(id)
 	if err != nil {
-		return fmt.Errorf("billing cycle not found")
+		return utils.NewNotFoundError("billing cycle")
 	}
 
 	if billingCycle.SystemDefined {
-		return fmt.Errorf("cannot delete system-defined billing cycle")
+		return utils.NewForbiddenError("cannot delete system-defined billing cycle")
 	}
 
 	if billingCycle.UserID == nil || *billingCycle.UserID != userID {
-		return fmt.Errorf("billing cycle not found")
+		return utils.NewNotFoundError("billing cycle")
+	}
+
+	if reassignTo != nil {
+		if *reassignTo == id {
+			return utils.NewValidationError("reassignTo", "cannot reassign to the billing cycle being deleted")
+		}
+		if _, err := s.GetByID(*reassignTo, userID); err != nil {
+			return err
+		}
 	}
 
-	return s.billingCycleRepo.Delete(billingCycle)
+	return s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		ids := []models.ULID{id}
+		if reassignTo != nil {
+			ids = append(ids, *reassignTo)
+		}
+		locked, err := s.billingCycleRepo.WithTx(tx).LockByIDs(ids...)
+		if err != nil {
+			return err
+		}
+		if len(locked) != len(ids) {
+			return utils.NewNotFoundError("billing cycle")
+		}
+
+		subscriptions, err := s.subscriptionRepo.WithTx(tx).GetByBillingCycle(id, userID)
+		if err != nil {
+			return err
+		}
+		if len(subscriptions) > 0 {
+			if reassignTo == nil {
+				return inUseError("billing cycle", subscriptions)
+			}
+			if _, err := s.subscriptionRepo.WithTx(tx).ReassignBillingCycle(userID, id, *reassignTo); err != nil {
+				return err
+			}
+		}
+		return s.billingCycleRepo.WithTx(tx).Delete(billingCycle)
+	})
 }
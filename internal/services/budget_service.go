@@ -0,0 +1,231 @@
+package services
+
+import (
+	"fmt"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type BudgetService struct {
+	budgetRepo       *repository.BudgetRepository
+	categoryRepo     *repository.CategoryRepository
+	currencyRepo     *repository.CurrencyRepository
+	analyticsRepo    *repository.AnalyticsRepository
+	exchangeRateRepo *repository.ExchangeRateRepository
+}
+
+type CreateBudgetRequest struct {
+	CategoryID string  `json:"categoryId" binding:"required"`
+	Amount     float64 `json:"amount" binding:"gt=0"` // Per month
+	CurrencyID string  `json:"currencyId" binding:"required"`
+}
+
+type UpdateBudgetRequest struct {
+	Amount     float64 `json:"amount" binding:"gt=0"`
+	CurrencyID string  `json:"currencyId" binding:"required"`
+}
+
+// BudgetStatus is a budget with the normalized monthly cost of the live
+// subscriptions in its category, in the budget's currency.
+type BudgetStatus struct {
+	Budget      models.Budget `json:"budget"`
+	Spent       float64       `json:"spent"`
+	Remaining   float64       `json:"remaining"`   // Negative when over budget
+	Utilization float64       `json:"utilization"` // Percentage of the budget spent
+	OverBudget  bool          `json:"overBudget"`
+	// Codes of currencies that could not be converted. Subscriptions in
+	// these currencies are left out of Spent.
+	MissingRates []string `json:"missingRates,omitempty"`
+}
+
+func NewBudgetService(
+	budgetRepo *repository.BudgetRepository,
+	categoryRepo *repository.CategoryRepository,
+	currencyRepo *repository.CurrencyRepository,
+	analyticsRepo *repository.AnalyticsRepository,
+	exchangeRateRepo *repository.ExchangeRateRepository,
+) *BudgetService {
+	return &BudgetService{
+		budgetRepo:       budgetRepo,
+		categoryRepo:     categoryRepo,
+		currencyRepo:     currencyRepo,
+		analyticsRepo:    analyticsRepo,
+		exchangeRateRepo: exchangeRateRepo,
+	}
+}
+
+func (s *BudgetService) Create(req *CreateBudgetRequest, userID models.ULID) (*BudgetStatus, error) {
+	var categoryID, currencyID models.ULID
+	if err := categoryID.UnmarshalJSON([]byte(`"` + req.CategoryID + `"`)); err != nil {
+		return nil, utils.NewValidationError("categoryId", "invalid format")
+	}
+	if err := currencyID.UnmarshalJSON([]byte(`"` + req.CurrencyID + `"`)); err != nil {
+		return nil, utils.NewValidationError("currencyId", "invalid format")
+	}
+
+	category, err := s.categoryRepo.GetByID(categoryID)
+	if err != nil {
+		return nil, utils.NewNotFoundError("category")
+	}
+	if !category.SystemDefined && (category.UserID == nil || *category.UserID != userID) {
+		return nil, utils.NewForbiddenError("category does not belong to user")
+	}
+	currency, err := s.currencyRepo.GetByID(currencyID)
+	if err != nil {
+		return nil, utils.NewNotFoundError("currency")
+	}
+
+	exists, err := s.budgetRepo.ExistsForCategory(categoryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, utils.NewDuplicateEntryError("budget for this category")
+	}
+
+	budget := &models.Budget{
+		UserID:     userID,
+		CategoryID: categoryID,
+		Category:   *category,
+		Amount:     req.Amount,
+		CurrencyID: currencyID,
+		Currency:   *currency,
+	}
+	if err := s.budgetRepo.Create(budget); err != nil {
+		return nil, err
+	}
+
+	return s.status(budget, userID)
+}
+
+// GetAll returns the user's budgets with their current utilization.
+func (s *BudgetService) GetAll(userID models.ULID) ([]BudgetStatus, error) {
+	budgets, err := s.budgetRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return budgetStatuses(s.analyticsRepo, s.exchangeRateRepo, budgets, userID)
+}
+
+func (s *BudgetService) GetByID(id, userID models.ULID) (*BudgetStatus, error) {
+	budget, err := s.getBudget(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.status(budget, userID)
+}
+
+func (s *BudgetService) Update(id models.ULID, req *UpdateBudgetRequest, userID models.ULID) (*BudgetStatus, error) {
+	budget, err := s.getBudget(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var currencyID models.ULID
+	if err := currencyID.UnmarshalJSON([]byte(`"` + req.CurrencyID + `"`)); err != nil {
+		return nil, utils.NewValidationError("currencyId", "invalid format")
+	}
+	currency, err := s.currencyRepo.GetByID(currencyID)
+	if err != nil {
+		return nil, utils.NewNotFoundError("currency")
+	}
+
+	budget.Amount = req.Amount
+	budget.CurrencyID = currencyID
+	budget.Currency = *currency
+	if err := s.budgetRepo.Update(budget); err != nil {
+		return nil, err
+	}
+
+	return s.status(budget, userID)
+}
+
+func (s *BudgetService) Delete(id, userID models.ULID) error {
+	budget, err := s.getBudget(id, userID)
+	if err != nil {
+		return err
+	}
+	return s.budgetRepo.Delete(budget)
+}
+
+func (s *BudgetService) getBudget(id, userID models.ULID) (*models.Budget, error) {
+	budget, err := s.budgetRepo.GetByID(id, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewNotFoundError("budget")
+		}
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (s *BudgetService) status(budget *models.Budget, userID models.ULID) (*BudgetStatus, error) {
+	statuses, err := budgetStatuses(s.analyticsRepo, s.exchangeRateRepo, []models.Budget{*budget}, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &statuses[0], nil
+}
+
+// budgetStatuses computes the utilization of budgets from the normalized
+// spend per category, converting each currency's total to the budget's
+// currency with the latest exchange rates.
+func budgetStatuses(
+	analyticsRepo *repository.AnalyticsRepository,
+	exchangeRateRepo *repository.ExchangeRateRepository,
+	budgets []models.Budget,
+	userID models.ULID,
+) ([]BudgetStatus, error) {
+	statuses := make([]BudgetStatus, len(budgets))
+	if len(budgets) == 0 {
+		return statuses, nil
+	}
+
+	totals, err := analyticsRepo.SpendByCategory(userID)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := loadRateTable(exchangeRateRepo, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for i, budget := range budgets {
+		status := &statuses[i]
+		status.Budget = budget
+
+		var spent float64
+		for _, total := range totals {
+			if total.GroupID != budget.CategoryID {
+				continue
+			}
+			amount, _, ok := rates.convert(total.Monthly, total.CurrencyID, budget.CurrencyID)
+			if !ok {
+				status.MissingRates = append(status.MissingRates, total.CurrencyCode)
+				continue
+			}
+			spent += amount
+		}
+
+		status.Spent = roundCents(spent)
+		status.Remaining = roundCents(budget.Amount - spent)
+		status.OverBudget = status.Spent > budget.Amount
+		if budget.Amount > 0 {
+			status.Utilization = roundCents(spent / budget.Amount * 100)
+		}
+	}
+	return statuses, nil
+}
+
+// overBudgetWarning describes a budget that has been exceeded.
+func overBudgetWarning(status *BudgetStatus) utils.Warning {
+	return utils.Warning{
+		Code: utils.WarningOverBudget,
+		Message: fmt.Sprintf("%s is over its monthly budget: %.2f of %.2f %s",
+			status.Budget.Category.Name, status.Spent, status.Budget.Amount, status.Budget.Currency.Code),
+	}
+}
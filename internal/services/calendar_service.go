@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"subscription-tracker/internal/auth"
+	"subscription-tracker/internal/calendar"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CalendarService struct {
+	calendarTokenRepo *repository.CalendarTokenRepository
+	subscriptionRepo  *repository.SubscriptionRepository
+}
+
+// CalendarTokenResponse carries a newly issued feed token. The token is only
+// returned once; afterwards only its hash is stored.
+type CalendarTokenResponse struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"` // Set by the handler, which knows the public host
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NewCalendarService(
+	calendarTokenRepo *repository.CalendarTokenRepository,
+	subscriptionRepo *repository.SubscriptionRepository,
+) *CalendarService {
+	return &CalendarService{
+		calendarTokenRepo: calendarTokenRepo,
+		subscriptionRepo:  subscriptionRepo,
+	}
+}
+
+// CreateToken issues a new feed token, revoking any previous one so only the
+// latest URL keeps working.
+func (s *CalendarService) CreateToken(userID models.ULID) (*CalendarTokenResponse, error) {
+	token, hash, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return nil, utils.NewInternalError("failed to generate token")
+	}
+
+	if err := s.calendarTokenRepo.RevokeAllForUser(userID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	calendarToken := &models.CalendarToken{
+		UserID:    userID,
+		TokenHash: hash,
+	}
+	if err := s.calendarTokenRepo.Create(calendarToken); err != nil {
+		return nil, err
+	}
+
+	return &CalendarTokenResponse{
+		Token:     token,
+		CreatedAt: calendarToken.CreatedAt,
+	}, nil
+}
+
+func (s *CalendarService) RevokeToken(userID models.ULID) error {
+	return s.calendarTokenRepo.RevokeAllForUser(userID, time.Now())
+}
+
+// Feed renders the iCalendar feed of the user owning token, with one
+// recurring event per active subscription.
+func (s *CalendarService) Feed(token string) ([]byte, error) {
+	calendarToken, err := s.calendarTokenRepo.GetActiveByHash(auth.HashOpaqueToken(token))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewNotFoundError("calendar")
+		}
+		return nil, err
+	}
+
+	subscriptions, err := s.subscriptionRepo.GetActiveWithDetails(calendarToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]calendar.Event, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		if !subscription.BilledOn(subscription.NextBillingDate) {
+			continue
+		}
+		events = append(events, subscriptionEvent(&subscription))
+	}
+
+	now := time.Now()
+	if err := s.calendarTokenRepo.TouchLastUsed(calendarToken, now); err != nil {
+		return nil, err
+	}
+
+	return calendar.Render("Subscription Renewals", events, now), nil
+}
+
+func subscriptionEvent(subscription *models.Subscription) calendar.Event {
+	start := subscription.NextBillingDate.UTC()
+	amount := strconv.FormatFloat(subscription.BilledAmount(), 'f', 2, 64)
+
+	description := fmt.Sprintf("%s %s", amount, subscription.Currency.Code)
+	if subscription.PaymentMethod.Name != "" {
+		description += " via " + subscription.PaymentMethod.Name
+	}
+
+	rrule := calendar.RecurrenceRule(subscription.BillingCycle, start, subscription.AnchorDay())
+	if subscription.CancelsAt != nil {
+		// UNTIL is inclusive; the last renewal is the day before cancellation.
+		rrule += ";UNTIL=" + subscription.CancelsAt.UTC().AddDate(0, 0, -1).Format("20060102")
+	}
+
+	return calendar.Event{
+		UID:         subscription.ID.String() + "@subscription-tracker",
+		Summary:     fmt.Sprintf("%s renewal (%s %s)", subscription.Name, amount, subscription.Currency.Code),
+		Description: description,
+		Start:       start,
+		RRule:       rrule,
+		AlarmDays:   subscription.ReminderDays,
+	}
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const cancellationBatchSize = 100
+
+type CancellationService struct {
+	cancellationRepo *repository.CancellationRepository
+	subscriptionRepo *repository.SubscriptionRepository
+	billingCycleRepo *repository.BillingCycleRepository
+}
+
+type CancelSubscriptionRequest struct {
+	// Defaults to the next billing date, so the subscription stays usable
+	// for the period already paid for.
+	EffectiveDate      *time.Time `json:"effectiveDate"`
+	Reason             string     `json:"reason"`
+	ConfirmationNumber string     `json:"confirmationNumber"`
+}
+
+// CancellationSavings reports the renewals a cancelled subscription would
+// have been charged for since its cancellation took effect.
+type CancellationSavings struct {
+	SubscriptionID   models.ULID          `json:"subscriptionId"`
+	SubscriptionName string               `json:"subscriptionName"`
+	Cancellation     *models.Cancellation `json:"cancellation"`
+	MissedCharges    int                  `json:"missedCharges"`
+	AmountSaved      float64              `json:"amountSaved"`
+	CurrencyID       models.ULID          `json:"currencyId"`
+	AsOf             time.Time            `json:"asOf"`
+}
+
+func NewCancellationService(
+	cancellationRepo *repository.CancellationRepository,
+	subscriptionRepo *repository.SubscriptionRepository,
+	billingCycleRepo *repository.BillingCycleRepository,
+) *CancellationService {
+	return &CancellationService{
+		cancellationRepo: cancellationRepo,
+		subscriptionRepo: subscriptionRepo,
+		billingCycleRepo: billingCycleRepo,
+	}
+}
+
+// Cancel records a cancellation. A subscription cancelled with a future
+// effective date becomes pending_cancellation and stays live, without being
+// billed again, until that date; otherwise it is cancelled right away.
+// Cancelling a subscription that is already pending cancellation replaces
+// the earlier record.
+func (s *CancellationService) Cancel(subscriptionID models.ULID, req *CancelSubscriptionRequest, userID models.ULID) (*models.Cancellation, error) {
+	subscription, err := s.getSubscription(subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	effectiveDate := now
+	if req.EffectiveDate != nil {
+		effectiveDate = *req.EffectiveDate
+	} else if subscription.NextBillingDate.After(now) && subscription.Status.CanTransitionTo(models.SubscriptionStatusPendingCancellation) {
+		effectiveDate = subscription.NextBillingDate
+	}
+
+	status := models.SubscriptionStatusCancelled
+	if effectiveDate.After(now) {
+		status = models.SubscriptionStatusPendingCancellation
+	}
+	rescheduling := subscription.Status == models.SubscriptionStatusPendingCancellation &&
+		status == models.SubscriptionStatusPendingCancellation
+	if !rescheduling && !subscription.Status.CanTransitionTo(status) {
+		return nil, utils.NewValidationError("status",
+			fmt.Sprintf("cannot change a %s subscription to %s", subscription.Status, status))
+	}
+
+	cancellation := &models.Cancellation{
+		UserID:             userID,
+		SubscriptionID:     subscription.ID,
+		CancelledAt:        now,
+		EffectiveDate:      effectiveDate,
+		Reason:             req.Reason,
+		ConfirmationNumber: req.ConfirmationNumber,
+		Amount:             subscription.BilledAmount(),
+		CurrencyID:         subscription.CurrencyID,
+	}
+
+	subscription.Status = status
+	subscription.CancelsAt = &effectiveDate
+
+	err = s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		cancellationRepo := s.cancellationRepo.WithTx(tx)
+		if err := cancellationRepo.WithdrawAll(subscription.ID, now); err != nil {
+			return err
+		}
+		if err := cancellationRepo.Create(cancellation); err != nil {
+			return err
+		}
+		return s.subscriptionRepo.WithTx(tx).UpdateFields(subscription, "status", "cancels_at")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cancellation, nil
+}
+
+// Savings reports the money saved since the subscription's cancellation took
+// effect.
+func (s *CancellationService) Savings(subscriptionID, userID models.ULID) (*CancellationSavings, error) {
+	subscription, err := s.getSubscription(subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cancellation, err := s.cancellationRepo.GetCurrent(subscription.ID, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewNotFoundError("cancellation")
+		}
+		return nil, err
+	}
+
+	billingCycles, err := billingCyclesFor(s.billingCycleRepo, []models.Subscription{*subscription})
+	if err != nil {
+		return nil, err
+	}
+
+	return cancellationSavings(subscription, billingCycles[subscription.BillingCycleID], cancellation, time.Now()), nil
+}
+
+// AllSavings reports the money saved for every cancelled subscription of the
+// user.
+func (s *CancellationService) AllSavings(userID models.ULID) ([]CancellationSavings, error) {
+	subscriptions, err := s.subscriptionRepo.GetAll(userID, models.SubscriptionStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+
+	cancellations, err := s.cancellationRepo.GetAllCurrent(userID)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[models.ULID]*models.Cancellation, len(cancellations))
+	for i := range cancellations {
+		if _, ok := latest[cancellations[i].SubscriptionID]; !ok {
+			latest[cancellations[i].SubscriptionID] = &cancellations[i]
+		}
+	}
+
+	billingCycles, err := billingCyclesFor(s.billingCycleRepo, subscriptions)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	savings := make([]CancellationSavings, 0, len(subscriptions))
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		cancellation, ok := latest[subscription.ID]
+		if !ok {
+			continue
+		}
+		savings = append(savings, *cancellationSavings(subscription, billingCycles[subscription.BillingCycleID], cancellation, now))
+	}
+	return savings, nil
+}
+
+// ExpireDue moves subscriptions pending cancellation to cancelled once their
+// cancellation takes effect. It returns the number of subscriptions
+// cancelled.
+func (s *CancellationService) ExpireDue(now time.Time) (int, error) {
+	cancelled := 0
+	for {
+		var batch int
+		err := s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+			subscriptionRepo := s.subscriptionRepo.WithTx(tx)
+
+			subscriptions, err := subscriptionRepo.LockDueCancellations(now, cancellationBatchSize)
+			if err != nil {
+				return err
+			}
+			batch = len(subscriptions)
+
+			for i := range subscriptions {
+				subscription := &subscriptions[i]
+				subscription.Status = models.SubscriptionStatusCancelled
+				if err := subscriptionRepo.UpdateFields(subscription, "status"); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return cancelled, err
+		}
+
+		cancelled += batch
+		if batch < cancellationBatchSize {
+			return cancelled, nil
+		}
+	}
+}
+
+func (s *CancellationService) getSubscription(id, userID models.ULID) (*models.Subscription, error) {
+	subscription, err := s.subscriptionRepo.GetByID(id, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewNotFoundError("subscription")
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// cancellationSavings counts the renewals that would have been charged from
+// the cancellation's effective date up to now. The dates are counted rather
+// than collected, as a daily subscription cancelled long ago misses more
+// renewals than a projection may expand.
+func cancellationSavings(subscription *models.Subscription, billingCycle models.BillingCycle, cancellation *models.Cancellation, now time.Time) *CancellationSavings {
+	projected := models.Subscription{
+		NextBillingDate:  cancellation.EffectiveDate,
+		BillingAnchorDay: subscription.AnchorDay(),
+		BillingCycle:     billingCycle,
+	}
+	missed := 0
+	projected.EachOccurrence(cancellation.EffectiveDate, now, func(time.Time) bool {
+		missed++
+		return true
+	})
+
+	return &CancellationSavings{
+		SubscriptionID:   subscription.ID,
+		SubscriptionName: subscription.Name,
+		Cancellation:     cancellation,
+		MissedCharges:    missed,
+		AmountSaved:      math.Round(float64(missed)*cancellation.Amount*100) / 100,
+		CurrencyID:       cancellation.CurrencyID,
+		AsOf:             now,
+	}
+}
@@ -10,7 +10,9 @@ import (
 )
 
 type CategoryService struct {
-	categoryRepo *repository.CategoryRepository
+	categoryRepo     *repository.CategoryRepository
+	subscriptionRepo *repository.SubscriptionRepository
+	budgetRepo       *repository.BudgetRepository
 }
 
 type CreateCategoryRequest struct {
@@ -21,9 +23,15 @@ type UpdateCategoryRequest struct {
 	Name string `json:"name" binding:"required"`
 }
 
-func NewCategoryService(categoryRepo *repository.CategoryRepository) *CategoryService {
+func NewCategoryService(
+	categoryRepo *repository.CategoryRepository,
+	subscriptionRepo *repository.SubscriptionRepository,
+	budgetRepo *repository.BudgetRepository,
+) *CategoryService {
 	return &CategoryService{
-		categoryRepo: categoryRepo,
+		categoryRepo:     categoryRepo,
+		subscriptionRepo: subscriptionRepo,
+		budgetRepo:       budgetRepo,
 	}
 }
 
@@ -79,7 +87,10 @@ func (s *CategoryService) Update(id models.ULID, req *UpdateCategoryRequest, use
 	return category, nil
 }
 
-func (s *CategoryService) Delete(id models.ULID, userID models.ULID) error {
+// Delete removes a category along with its budget. A category still used by
+// subscriptions is only deleted when reassignTo names another category to
+// move them to; otherwise a conflict listing them is returned.
+func (s *CategoryService) Delete(id models.ULID, userID models.ULID, reassignTo *models.ULID) error {
 	category, err := s.GetByID(id, userID)
 	if err != nil {
 		return err
@@ -89,7 +100,45 @@ func (s *CategoryService) Delete(id models.ULID, userID models.ULID) error {
 		return utils.NewForbiddenError("system-defined categories cannot be deleted")
 	}
 
-	return s.categoryRepo.Delete(category)
+	if reassignTo != nil {
+		if *reassignTo == id {
+			return utils.NewValidationError("reassignTo", "cannot reassign to the category being deleted")
+		}
+		if _, err := s.GetByID(*reassignTo, userID); err != nil {
+			return err
+		}
+	}
+
+	return s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		ids := []models.ULID{id}
+		if reassignTo != nil {
+			ids = append(ids, *reassignTo)
+		}
+		locked, err := s.categoryRepo.WithTx(tx).LockByIDs(ids...)
+		if err != nil {
+			return err
+		}
+		if len(locked) != len(ids) {
+			return utils.NewNotFoundError("category")
+		}
+
+		subscriptions, err := s.subscriptionRepo.WithTx(tx).GetByCategory(id, userID)
+		if err != nil {
+			return err
+		}
+		if len(subscriptions) > 0 {
+			if reassignTo == nil {
+				return inUseError("category", subscriptions)
+			}
+			if _, err := s.subscriptionRepo.WithTx(tx).ReassignCategory(userID, id, *reassignTo); err != nil {
+				return err
+			}
+		}
+		if err := s.budgetRepo.WithTx(tx).DeleteByCategory(id); err != nil {
+			return err
+		}
+		return s.categoryRepo.WithTx(tx).Delete(category)
+	})
 }
 
 func (s *CategoryService) GetByID(id, userID models.ULID) (*models.Category, error) {
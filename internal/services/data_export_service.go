@@ -0,0 +1,611 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"log"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// dataExportBatchSize is the number of exports built per transaction; each
+// reads all of one user's data.
+const dataExportBatchSize = 5
+
+// dataExportRetention is how long a built archive can be downloaded.
+const dataExportRetention = 7 * 24 * time.Hour
+
+// dataExportVersion is bumped when the layout of the archive changes.
+const dataExportVersion = 1
+
+type DataExportService struct {
+	dataExportRepo    *repository.DataExportRepository
+	userRepo          *repository.UserRepository
+	currencyRepo      *repository.CurrencyRepository
+	subscriptionRepo  *repository.SubscriptionRepository
+	paymentMethodRepo *repository.PaymentMethodRepository
+	categoryRepo      *repository.CategoryRepository
+	billingCycleRepo  *repository.BillingCycleRepository
+	paymentRepo       *repository.PaymentRepository
+	priceChangeRepo   *repository.PriceChangeRepository
+	reminderRepo      *repository.ReminderRepository
+	budgetRepo        *repository.BudgetRepository
+	pauseWindowRepo   *repository.PauseWindowRepository
+	cancellationRepo  *repository.CancellationRepository
+}
+
+// DataExportManifest describes the files of an export archive. It is
+// written as manifest.json next to them; Version changes whenever files are
+// added, removed or change shape, and Records counts the entries of each
+// file.
+type DataExportManifest struct {
+	Version     int                      `json:"version"`
+	UserID      models.ULID              `json:"userId"`
+	GeneratedAt time.Time                `json:"generatedAt"`
+	Files       []DataExportManifestFile `json:"files"`
+}
+
+type DataExportManifestFile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Records     int    `json:"records"`
+}
+
+// The exported* types are the archive's view of the models: every stored
+// field of the user's rows, with references by ID and no internal fields.
+
+type exportedSubscription struct {
+	ID               models.ULID               `json:"id"`
+	Name             string                    `json:"name"`
+	Description      string                    `json:"description"`
+	Amount           float64                   `json:"amount"`
+	Currency         string                    `json:"currency"`
+	CategoryID       models.ULID               `json:"categoryId"`
+	Category         string                    `json:"category"`
+	BillingCycleID   models.ULID               `json:"billingCycleId"`
+	BillingCycle     string                    `json:"billingCycle"`
+	PaymentMethodID  models.ULID               `json:"paymentMethodId"`
+	PaymentMethod    string                    `json:"paymentMethod"`
+	Status           models.SubscriptionStatus `json:"status"`
+	NextBillingDate  time.Time                 `json:"nextBillingDate"`
+	BillingAnchorDay int                       `json:"billingAnchorDay"`
+	ReminderDays     int                       `json:"reminderDays"`
+	TrialStartDate   *time.Time                `json:"trialStartDate"`
+	TrialEndDate     *time.Time                `json:"trialEndDate"`
+	PostTrialAmount  *float64                  `json:"postTrialAmount"`
+	TrialConvertedAt *time.Time                `json:"trialConvertedAt"`
+	CancelsAt        *time.Time                `json:"cancelsAt"`
+	CreatedAt        time.Time                 `json:"createdAt"`
+	UpdatedAt        time.Time                 `json:"updatedAt"`
+	DeletedAt        *time.Time                `json:"deletedAt"`
+}
+
+type exportedPaymentMethod struct {
+	ID          models.ULID              `json:"id"`
+	Name        string                   `json:"name"`
+	Type        models.PaymentMethodType `json:"type"`
+	LastFour    string                   `json:"lastFour"`
+	ExpiryMonth *int                     `json:"expiryMonth"`
+	ExpiryYear  *int                     `json:"expiryYear"`
+	ArchivedAt  *time.Time               `json:"archivedAt"`
+	CreatedAt   time.Time                `json:"createdAt"`
+	UpdatedAt   time.Time                `json:"updatedAt"`
+}
+
+type exportedCategory struct {
+	ID        models.ULID `json:"id"`
+	Name      string      `json:"name"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+type exportedBillingCycle struct {
+	ID        models.ULID             `json:"id"`
+	Name      string                  `json:"name"`
+	Interval  int                     `json:"interval"`
+	Unit      models.BillingCycleUnit `json:"unit"`
+	CreatedAt time.Time               `json:"createdAt"`
+	UpdatedAt time.Time               `json:"updatedAt"`
+}
+
+type exportedPayment struct {
+	ID              models.ULID          `json:"id"`
+	SubscriptionID  models.ULID          `json:"subscriptionId"`
+	PaymentMethodID models.ULID          `json:"paymentMethodId"`
+	Amount          float64              `json:"amount"`
+	Currency        string               `json:"currency"`
+	PaidAt          time.Time            `json:"paidAt"`
+	Source          models.PaymentSource `json:"source"`
+	Notes           string               `json:"notes"`
+	CreatedAt       time.Time            `json:"createdAt"`
+	UpdatedAt       time.Time            `json:"updatedAt"`
+}
+
+type exportedPriceChange struct {
+	ID             models.ULID `json:"id"`
+	SubscriptionID models.ULID `json:"subscriptionId"`
+	Amount         float64     `json:"amount"`
+	Currency       string      `json:"currency"`
+	EffectiveDate  time.Time   `json:"effectiveDate"`
+	AppliedAt      *time.Time  `json:"appliedAt"`
+	Note           string      `json:"note"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+}
+
+type exportedReminder struct {
+	ID             models.ULID `json:"id"`
+	SubscriptionID models.ULID `json:"subscriptionId"`
+	BillingDate    time.Time   `json:"billingDate"`
+	RemindAt       time.Time   `json:"remindAt"`
+	SentAt         *time.Time  `json:"sentAt"`
+	Attempts       int         `json:"attempts"`
+	LastError      string      `json:"lastError"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+}
+
+type exportedBudget struct {
+	ID         models.ULID `json:"id"`
+	CategoryID models.ULID `json:"categoryId"`
+	Category   string      `json:"category"`
+	Amount     float64     `json:"amount"`
+	Currency   string      `json:"currency"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+}
+
+type exportedPauseWindow struct {
+	ID             models.ULID `json:"id"`
+	SubscriptionID models.ULID `json:"subscriptionId"`
+	StartDate      time.Time   `json:"startDate"`
+	EndDate        *time.Time  `json:"endDate"`
+	StartedAt      *time.Time  `json:"startedAt"`
+	ResumedAt      *time.Time  `json:"resumedAt"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+}
+
+type exportedCancellation struct {
+	ID                 models.ULID `json:"id"`
+	SubscriptionID     models.ULID `json:"subscriptionId"`
+	CancelledAt        time.Time   `json:"cancelledAt"`
+	EffectiveDate      time.Time   `json:"effectiveDate"`
+	Reason             string      `json:"reason"`
+	ConfirmationNumber string      `json:"confirmationNumber"`
+	Amount             float64     `json:"amount"`
+	Currency           string      `json:"currency"`
+	WithdrawnAt        *time.Time  `json:"withdrawnAt"`
+	CreatedAt          time.Time   `json:"createdAt"`
+	UpdatedAt          time.Time   `json:"updatedAt"`
+}
+
+// exportFile is one JSON file of the archive.
+type exportFile struct {
+	name        string
+	description string
+	records     int
+	data        interface{}
+}
+
+func NewDataExportService(
+	dataExportRepo *repository.DataExportRepository,
+	userRepo *repository.UserRepository,
+	currencyRepo *repository.CurrencyRepository,
+	subscriptionRepo *repository.SubscriptionRepository,
+	paymentMethodRepo *repository.PaymentMethodRepository,
+	categoryRepo *repository.CategoryRepository,
+	billingCycleRepo *repository.BillingCycleRepository,
+	paymentRepo *repository.PaymentRepository,
+	priceChangeRepo *repository.PriceChangeRepository,
+	reminderRepo *repository.ReminderRepository,
+	budgetRepo *repository.BudgetRepository,
+	pauseWindowRepo *repository.PauseWindowRepository,
+	cancellationRepo *repository.CancellationRepository,
+) *DataExportService {
+	return &DataExportService{
+		dataExportRepo:    dataExportRepo,
+		userRepo:          userRepo,
+		currencyRepo:      currencyRepo,
+		subscriptionRepo:  subscriptionRepo,
+		paymentMethodRepo: paymentMethodRepo,
+		categoryRepo:      categoryRepo,
+		billingCycleRepo:  billingCycleRepo,
+		paymentRepo:       paymentRepo,
+		priceChangeRepo:   priceChangeRepo,
+		reminderRepo:      reminderRepo,
+		budgetRepo:        budgetRepo,
+		pauseWindowRepo:   pauseWindowRepo,
+		cancellationRepo:  cancellationRepo,
+	}
+}
+
+// Request queues an export of the user's data. While one is waiting to be
+// built it is returned instead of queueing another; created reports
+// whether a new export was queued.
+func (s *DataExportService) Request(userID models.ULID) (export *models.DataExport, created bool, err error) {
+	export, err = s.dataExportRepo.GetPending(userID)
+	if err == nil {
+		return export, false, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, false, err
+	}
+
+	export = &models.DataExport{
+		UserID: userID,
+		Status: models.DataExportStatusPending,
+	}
+	if err := s.dataExportRepo.Create(export); err != nil {
+		return nil, false, err
+	}
+	return export, true, nil
+}
+
+// GetLatest returns the status of the user's most recent export.
+func (s *DataExportService) GetLatest(userID models.ULID) (*models.DataExport, error) {
+	export, err := s.dataExportRepo.GetLatest(userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewNotFoundError("data export")
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// Download returns the user's most recent export that is ready and has not
+// expired, including its archive.
+func (s *DataExportService) Download(userID models.ULID) (*models.DataExport, error) {
+	export, err := s.dataExportRepo.GetLatestReady(userID, time.Now())
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewNotFoundError("ready data export")
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// BuildPending builds the archives of all queued exports. An export whose
+// archive cannot be built is marked failed so the user can request another.
+// It returns the number of exports processed.
+func (s *DataExportService) BuildPending(now time.Time) (int, error) {
+	processed := 0
+	for {
+		var batch int
+		err := s.dataExportRepo.Transaction(func(tx *gorm.DB) error {
+			dataExportRepo := s.dataExportRepo.WithTx(tx)
+
+			exports, err := dataExportRepo.LockPending(dataExportBatchSize)
+			if err != nil {
+				return err
+			}
+			batch = len(exports)
+
+			for i := range exports {
+				export := &exports[i]
+				completedAt := now
+				export.CompletedAt = &completedAt
+
+				archive, err := s.buildArchive(export.UserID, now)
+				if err != nil {
+					log.Printf("Failed to build data export %s: %v", export.ID, err)
+					export.Status = models.DataExportStatusFailed
+					export.Error = "the archive could not be built; request a new export"
+					if err := dataExportRepo.UpdateFields(export, "status", "error", "completed_at"); err != nil {
+						return err
+					}
+					continue
+				}
+
+				expiresAt := now.Add(dataExportRetention)
+				export.Status = models.DataExportStatusReady
+				export.Archive = archive
+				export.Size = int64(len(archive))
+				export.ExpiresAt = &expiresAt
+				if err := dataExportRepo.UpdateFields(export, "status", "archive", "size", "completed_at", "expires_at"); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return processed, err
+		}
+
+		processed += batch
+		if batch < dataExportBatchSize {
+			return processed, nil
+		}
+	}
+}
+
+// DeleteExpired removes exports whose download period has ended.
+func (s *DataExportService) DeleteExpired(now time.Time) (int64, error) {
+	return s.dataExportRepo.DeleteExpired(now)
+}
+
+// buildArchive collects the user's data and writes it as a ZIP archive of
+// JSON files, listed in manifest.json.
+func (s *DataExportService) buildArchive(userID models.ULID, now time.Time) ([]byte, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.BaseCurrencyID != nil {
+		if user.BaseCurrency, err = s.currencyRepo.GetByID(*user.BaseCurrencyID); err != nil {
+			return nil, err
+		}
+	}
+
+	subscriptions, err := s.subscriptionRepo.GetAllWithDeleted(userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedSubscriptions := make([]exportedSubscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		var deletedAt *time.Time
+		if subscription.DeletedAt.Valid {
+			deletedAt = &subscription.DeletedAt.Time
+		}
+		exportedSubscriptions = append(exportedSubscriptions, exportedSubscription{
+			ID:               subscription.ID,
+			Name:             subscription.Name,
+			Description:      subscription.Description,
+			Amount:           subscription.Amount,
+			Currency:         subscription.Currency.Code,
+			CategoryID:       subscription.CategoryID,
+			Category:         subscription.Category.Name,
+			BillingCycleID:   subscription.BillingCycleID,
+			BillingCycle:     subscription.BillingCycle.Name,
+			PaymentMethodID:  subscription.PaymentMethodID,
+			PaymentMethod:    subscription.PaymentMethod.Name,
+			Status:           subscription.Status,
+			NextBillingDate:  subscription.NextBillingDate,
+			BillingAnchorDay: subscription.BillingAnchorDay,
+			ReminderDays:     subscription.ReminderDays,
+			TrialStartDate:   subscription.TrialStartDate,
+			TrialEndDate:     subscription.TrialEndDate,
+			PostTrialAmount:  subscription.PostTrialAmount,
+			TrialConvertedAt: subscription.TrialConvertedAt,
+			CancelsAt:        subscription.CancelsAt,
+			CreatedAt:        subscription.CreatedAt,
+			UpdatedAt:        subscription.UpdatedAt,
+			DeletedAt:        deletedAt,
+		})
+	}
+
+	payments, err := s.paymentRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedPayments := make([]exportedPayment, 0, len(payments))
+	for _, payment := range payments {
+		exportedPayments = append(exportedPayments, exportedPayment{
+			ID:              payment.ID,
+			SubscriptionID:  payment.SubscriptionID,
+			PaymentMethodID: payment.PaymentMethodID,
+			Amount:          payment.Amount,
+			Currency:        payment.Currency.Code,
+			PaidAt:          payment.PaidAt,
+			Source:          payment.Source,
+			Notes:           payment.Notes,
+			CreatedAt:       payment.CreatedAt,
+			UpdatedAt:       payment.UpdatedAt,
+		})
+	}
+
+	priceChanges, err := s.priceChangeRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedPriceChanges := make([]exportedPriceChange, 0, len(priceChanges))
+	for _, priceChange := range priceChanges {
+		exportedPriceChanges = append(exportedPriceChanges, exportedPriceChange{
+			ID:             priceChange.ID,
+			SubscriptionID: priceChange.SubscriptionID,
+			Amount:         priceChange.Amount,
+			Currency:       priceChange.Currency.Code,
+			EffectiveDate:  priceChange.EffectiveDate,
+			AppliedAt:      priceChange.AppliedAt,
+			Note:           priceChange.Note,
+			CreatedAt:      priceChange.CreatedAt,
+			UpdatedAt:      priceChange.UpdatedAt,
+		})
+	}
+
+	reminders, err := s.reminderRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedReminders := make([]exportedReminder, 0, len(reminders))
+	for _, reminder := range reminders {
+		exportedReminders = append(exportedReminders, exportedReminder{
+			ID:             reminder.ID,
+			SubscriptionID: reminder.SubscriptionID,
+			BillingDate:    reminder.BillingDate,
+			RemindAt:       reminder.RemindAt,
+			SentAt:         reminder.SentAt,
+			Attempts:       reminder.Attempts,
+			LastError:      reminder.LastError,
+			CreatedAt:      reminder.CreatedAt,
+			UpdatedAt:      reminder.UpdatedAt,
+		})
+	}
+
+	pauseWindows, err := s.pauseWindowRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedPauseWindows := make([]exportedPauseWindow, 0, len(pauseWindows))
+	for _, pauseWindow := range pauseWindows {
+		exportedPauseWindows = append(exportedPauseWindows, exportedPauseWindow{
+			ID:             pauseWindow.ID,
+			SubscriptionID: pauseWindow.SubscriptionID,
+			StartDate:      pauseWindow.StartDate,
+			EndDate:        pauseWindow.EndDate,
+			StartedAt:      pauseWindow.StartedAt,
+			ResumedAt:      pauseWindow.ResumedAt,
+			CreatedAt:      pauseWindow.CreatedAt,
+			UpdatedAt:      pauseWindow.UpdatedAt,
+		})
+	}
+
+	cancellations, err := s.cancellationRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedCancellations := make([]exportedCancellation, 0, len(cancellations))
+	for _, cancellation := range cancellations {
+		exportedCancellations = append(exportedCancellations, exportedCancellation{
+			ID:                 cancellation.ID,
+			SubscriptionID:     cancellation.SubscriptionID,
+			CancelledAt:        cancellation.CancelledAt,
+			EffectiveDate:      cancellation.EffectiveDate,
+			Reason:             cancellation.Reason,
+			ConfirmationNumber: cancellation.ConfirmationNumber,
+			Amount:             cancellation.Amount,
+			Currency:           cancellation.Currency.Code,
+			WithdrawnAt:        cancellation.WithdrawnAt,
+			CreatedAt:          cancellation.CreatedAt,
+			UpdatedAt:          cancellation.UpdatedAt,
+		})
+	}
+
+	paymentMethods, err := s.paymentMethodRepo.GetAllForUser(userID, true)
+	if err != nil {
+		return nil, err
+	}
+	exportedPaymentMethods := make([]exportedPaymentMethod, 0, len(paymentMethods))
+	for _, paymentMethod := range paymentMethods {
+		exportedPaymentMethods = append(exportedPaymentMethods, exportedPaymentMethod{
+			ID:          paymentMethod.ID,
+			Name:        paymentMethod.Name,
+			Type:        paymentMethod.Type,
+			LastFour:    paymentMethod.LastFour,
+			ExpiryMonth: paymentMethod.ExpiryMonth,
+			ExpiryYear:  paymentMethod.ExpiryYear,
+			ArchivedAt:  paymentMethod.ArchivedAt,
+			CreatedAt:   paymentMethod.CreatedAt,
+			UpdatedAt:   paymentMethod.UpdatedAt,
+		})
+	}
+
+	// Only the user's own categories and billing cycles; the system ones
+	// hold no personal data.
+	categories, err := s.categoryRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedCategories := []exportedCategory{}
+	for _, category := range categories {
+		if category.SystemDefined {
+			continue
+		}
+		exportedCategories = append(exportedCategories, exportedCategory{
+			ID:        category.ID,
+			Name:      category.Name,
+			CreatedAt: category.CreatedAt,
+			UpdatedAt: category.UpdatedAt,
+		})
+	}
+
+	billingCycles, err := s.billingCycleRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedBillingCycles := []exportedBillingCycle{}
+	for _, billingCycle := range billingCycles {
+		if billingCycle.SystemDefined {
+			continue
+		}
+		exportedBillingCycles = append(exportedBillingCycles, exportedBillingCycle{
+			ID:        billingCycle.ID,
+			Name:      billingCycle.Name,
+			Interval:  billingCycle.Interval,
+			Unit:      billingCycle.Unit,
+			CreatedAt: billingCycle.CreatedAt,
+			UpdatedAt: billingCycle.UpdatedAt,
+		})
+	}
+
+	budgets, err := s.budgetRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	exportedBudgets := make([]exportedBudget, 0, len(budgets))
+	for _, budget := range budgets {
+		exportedBudgets = append(exportedBudgets, exportedBudget{
+			ID:         budget.ID,
+			CategoryID: budget.CategoryID,
+			Category:   budget.Category.Name,
+			Amount:     budget.Amount,
+			Currency:   budget.Currency.Code,
+			CreatedAt:  budget.CreatedAt,
+			UpdatedAt:  budget.UpdatedAt,
+		})
+	}
+
+	files := []exportFile{
+		{"profile.json", "Account profile and preferences", 1, newUserProfile(user)},
+		{"subscriptions.json", "Subscriptions, including cancelled, expired and deleted ones", len(exportedSubscriptions), exportedSubscriptions},
+		{"payments.json", "Recorded payments of every subscription", len(exportedPayments), exportedPayments},
+		{"price_changes.json", "Price history, including scheduled changes", len(exportedPriceChanges), exportedPriceChanges},
+		{"reminders.json", "Renewal reminders, sent and pending", len(exportedReminders), exportedReminders},
+		{"pause_windows.json", "Scheduled, running and past pauses", len(exportedPauseWindows), exportedPauseWindows},
+		{"cancellations.json", "Cancellations, including withdrawn ones", len(exportedCancellations), exportedCancellations},
+		{"payment_methods.json", "Payment methods, including archived ones", len(exportedPaymentMethods), exportedPaymentMethods},
+		{"categories.json", "Categories created by the user", len(exportedCategories), exportedCategories},
+		{"billing_cycles.json", "Billing cycles created by the user", len(exportedBillingCycles), exportedBillingCycles},
+		{"budgets.json", "Monthly budgets per category", len(exportedBudgets), exportedBudgets},
+	}
+
+	manifest := DataExportManifest{
+		Version:     dataExportVersion,
+		UserID:      userID,
+		GeneratedAt: now,
+		Files:       make([]DataExportManifestFile, 0, len(files)),
+	}
+	for _, file := range files {
+		manifest.Files = append(manifest.Files, DataExportManifestFile{
+			Name:        file.name,
+			Description: file.description,
+			Records:     file.records,
+		})
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	if err := writeJSONFile(archive, "manifest.json", manifest, now); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := writeJSONFile(archive, file.name, file.data, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSONFile(archive *zip.Writer, name string, data interface{}, modified time.Time) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"subscription-tracker/internal/fxrates"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// importHistoryLimit caps how many past imports are listed.
+	importHistoryLimit = 100
+	// manualRateSource is the source recorded for rates set by hand.
+	manualRateSource = "manual"
+)
+
+type ExchangeRateService struct {
+	exchangeRateRepo *repository.ExchangeRateRepository
+	currencyRepo     *repository.CurrencyRepository
+}
+
+type SetExchangeRateRequest struct {
+	BaseCurrencyID  string    `json:"baseCurrencyId" binding:"required"`
+	QuoteCurrencyID string    `json:"quoteCurrencyId" binding:"required"`
+	Rate            float64   `json:"rate" binding:"required,gt=0"` // Units of quote currency per unit of base currency
+	RateDate        time.Time `json:"rateDate" binding:"required"`
+}
+
+func NewExchangeRateService(
+	exchangeRateRepo *repository.ExchangeRateRepository,
+	currencyRepo *repository.CurrencyRepository,
+) *ExchangeRateService {
+	return &ExchangeRateService{
+		exchangeRateRepo: exchangeRateRepo,
+		currencyRepo:     currencyRepo,
+	}
+}
+
+// GetLatest returns the most recent rate of every currency pair as of date.
+func (s *ExchangeRateService) GetLatest(asOf time.Time) ([]models.ExchangeRate, error) {
+	return s.exchangeRateRepo.GetLatest(asOf)
+}
+
+// Set records the rate of a currency pair on a date, replacing any rate
+// already stored for that pair and date.
+func (s *ExchangeRateService) Set(req *SetExchangeRateRequest) (*models.ExchangeRate, error) {
+	var baseCurrencyID, quoteCurrencyID models.ULID
+	if err := baseCurrencyID.UnmarshalJSON([]byte(`"` + req.BaseCurrencyID + `"`)); err != nil {
+		return nil, utils.NewValidationError("baseCurrencyId", "invalid format")
+	}
+	if err := quoteCurrencyID.UnmarshalJSON([]byte(`"` + req.QuoteCurrencyID + `"`)); err != nil {
+		return nil, utils.NewValidationError("quoteCurrencyId", "invalid format")
+	}
+	if baseCurrencyID == quoteCurrencyID {
+		return nil, utils.NewValidationError("quoteCurrencyId", "quote currency must differ from base currency")
+	}
+
+	if _, err := s.currencyRepo.GetByID(baseCurrencyID); err != nil {
+		return nil, utils.NewNotFoundError("base currency")
+	}
+	if _, err := s.currencyRepo.GetByID(quoteCurrencyID); err != nil {
+		return nil, utils.NewNotFoundError("quote currency")
+	}
+
+	year, month, day := req.RateDate.Date()
+	rate := &models.ExchangeRate{
+		BaseCurrencyID:  baseCurrencyID,
+		QuoteCurrencyID: quoteCurrencyID,
+		Rate:            req.Rate,
+		RateDate:        time.Date(year, month, day, 0, 0, 0, 0, time.UTC),
+		Source:          manualRateSource,
+	}
+	if err := s.exchangeRateRepo.Upsert(rate); err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
+
+// Import loads a rates file. Rates for currencies that are not in the
+// currencies table are skipped and reported on the returned audit record;
+// any malformed entry rejects the whole file. importedBy is nil for imports
+// from the command line.
+func (s *ExchangeRateService) Import(format fxrates.Format, source string, r io.Reader, importedBy *models.ULID) (*models.ExchangeRateImport, error) {
+	if !fxrates.IsValidFormat(format) {
+		return nil, utils.NewValidationError("format", "format must be ecb or csv")
+	}
+	if source == "" {
+		source = string(format)
+	}
+
+	rates, err := fxrates.Parse(format, r)
+	if err != nil {
+		return nil, utils.NewValidationError("file", err.Error())
+	}
+
+	currencies, err := s.currencyRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	currencyIDs := make(map[string]models.ULID, len(currencies))
+	for _, currency := range currencies {
+		currencyIDs[currency.Code] = currency.ID
+	}
+
+	rateImport := &models.ExchangeRateImport{
+		Source:       source,
+		Format:       string(format),
+		ImportedByID: importedBy,
+		ImportedAt:   time.Now(),
+	}
+	unknown := make(map[string]bool)
+
+	err = s.exchangeRateRepo.Transaction(func(tx *gorm.DB) error {
+		exchangeRateRepo := s.exchangeRateRepo.WithTx(tx)
+		if err := exchangeRateRepo.CreateImport(rateImport); err != nil {
+			return err
+		}
+
+		for _, rate := range rates {
+			baseCurrencyID, baseKnown := currencyIDs[rate.Base]
+			quoteCurrencyID, quoteKnown := currencyIDs[rate.Quote]
+			if !baseKnown {
+				unknown[rate.Base] = true
+			}
+			if !quoteKnown {
+				unknown[rate.Quote] = true
+			}
+			if !baseKnown || !quoteKnown || baseCurrencyID == quoteCurrencyID {
+				rateImport.SkippedCount++
+				continue
+			}
+
+			err := exchangeRateRepo.Upsert(&models.ExchangeRate{
+				BaseCurrencyID:  baseCurrencyID,
+				QuoteCurrencyID: quoteCurrencyID,
+				Rate:            rate.Rate,
+				RateDate:        rate.Date,
+				Source:          source,
+				ImportID:        &rateImport.ID,
+			})
+			if err != nil {
+				return err
+			}
+			rateImport.RateCount++
+		}
+
+		codes := make([]string, 0, len(unknown))
+		for code := range unknown {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		rateImport.UnknownCurrencies = strings.Join(codes, ",")
+
+		return exchangeRateRepo.UpdateImport(rateImport)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rateImport, nil
+}
+
+// GetImports lists the most recent rate imports for auditing.
+func (s *ExchangeRateService) GetImports() ([]models.ExchangeRateImport, error) {
+	return s.exchangeRateRepo.GetImports(importHistoryLimit)
+}
+
+type currencyPair struct {
+	from, to models.ULID
+}
+
+// rateTable converts amounts using a snapshot of exchange rates. Pairs
+// without a stored rate are converted through their inverse or through a
+// third currency both sides have a rate for, e.g. USD -> EUR -> SGD with
+// euro reference rates.
+type rateTable struct {
+	rates      map[currencyPair]models.ExchangeRate
+	currencies []models.ULID // Sorted, so cross rates are picked deterministically
+}
+
+// loadRateTable loads the latest rates as of asOf.
+func loadRateTable(exchangeRateRepo *repository.ExchangeRateRepository, asOf time.Time) (*rateTable, error) {
+	rates, err := exchangeRateRepo.GetLatest(asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &rateTable{rates: make(map[currencyPair]models.ExchangeRate, len(rates))}
+	seen := make(map[models.ULID]bool)
+	for _, rate := range rates {
+		table.rates[currencyPair{rate.BaseCurrencyID, rate.QuoteCurrencyID}] = rate
+		for _, id := range []models.ULID{rate.BaseCurrencyID, rate.QuoteCurrencyID} {
+			if !seen[id] {
+				seen[id] = true
+				table.currencies = append(table.currencies, id)
+			}
+		}
+	}
+	sort.Slice(table.currencies, func(i, j int) bool {
+		return table.currencies[i].String() < table.currencies[j].String()
+	})
+	return table, nil
+}
+
+// convert returns amount in the to currency and the date of the rate used.
+// When a cross rate is needed the older of the two rate dates is reported.
+// The date is zero if no conversion was necessary.
+func (t *rateTable) convert(amount float64, from, to models.ULID) (float64, time.Time, bool) {
+	if from == to {
+		return amount, time.Time{}, true
+	}
+	if rate, date, ok := t.rate(from, to); ok {
+		return amount * rate, date, true
+	}
+
+	var best float64
+	var bestDate time.Time
+	found := false
+	for _, pivot := range t.currencies {
+		if pivot == from || pivot == to {
+			continue
+		}
+		first, firstDate, ok := t.rate(from, pivot)
+		if !ok {
+			continue
+		}
+		second, secondDate, ok := t.rate(pivot, to)
+		if !ok {
+			continue
+		}
+		date := firstDate
+		if secondDate.Before(date) {
+			date = secondDate
+		}
+		if !found || date.After(bestDate) {
+			best, bestDate, found = first*second, date, true
+		}
+	}
+	if !found {
+		return 0, time.Time{}, false
+	}
+	return amount * best, bestDate, true
+}
+
+// rate looks up the direct or inverse rate between two currencies.
+func (t *rateTable) rate(from, to models.ULID) (float64, time.Time, bool) {
+	if rate, ok := t.rates[currencyPair{from, to}]; ok {
+		return rate.Rate, rate.RateDate, true
+	}
+	if rate, ok := t.rates[currencyPair{to, from}]; ok && rate.Rate != 0 {
+		return 1 / rate.Rate, rate.RateDate, true
+	}
+	return 0, time.Time{}, false
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
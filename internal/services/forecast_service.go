@@ -0,0 +1,271 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+	"time"
+)
+
+const (
+	DefaultForecastMonths = 12
+	MaxForecastMonths     = 24
+)
+
+type ForecastService struct {
+	subscriptionRepo *repository.SubscriptionRepository
+	pauseWindowRepo  *repository.PauseWindowRepository
+	userRepo         *repository.UserRepository
+	currencyRepo     *repository.CurrencyRepository
+	exchangeRateRepo *repository.ExchangeRateRepository
+	priceService     *PriceService
+}
+
+// Forecast is the expected outflow of the user's live subscriptions, month
+// by month. The first month starts now, so it only covers the rest of the
+// current month and any charges that are overdue.
+type Forecast struct {
+	From         time.Time        `json:"from"`
+	To           time.Time        `json:"to"`
+	Months       []ForecastMonth  `json:"months"`
+	BaseCurrency *models.Currency `json:"baseCurrency,omitempty"`
+	MissingRates []string         `json:"missingRates,omitempty"` // Codes of currencies that could not be converted
+}
+
+// ForecastMonth breaks down the charges expected in one calendar month.
+// Amounts in different currencies are never added up, so every group is
+// split per currency; ConvertedTotal is the month's total in the user's base
+// currency, if one is set.
+type ForecastMonth struct {
+	Month           string          `json:"month"` // YYYY-MM
+	Totals          []ForecastTotal `json:"totals"`
+	ByPaymentMethod []ForecastTotal `json:"byPaymentMethod"`
+	ByCategory      []ForecastTotal `json:"byCategory"`
+	ConvertedTotal  *float64        `json:"convertedTotal,omitempty"`
+}
+
+// ForecastTotal is the sum of the charges in one group and currency.
+type ForecastTotal struct {
+	GroupID      models.ULID `json:"groupId"`
+	GroupName    string      `json:"groupName"`
+	CurrencyID   models.ULID `json:"currencyId"`
+	CurrencyCode string      `json:"currencyCode"`
+	Amount       float64     `json:"amount"`
+	Charges      int         `json:"charges"`
+}
+
+func NewForecastService(
+	subscriptionRepo *repository.SubscriptionRepository,
+	pauseWindowRepo *repository.PauseWindowRepository,
+	userRepo *repository.UserRepository,
+	currencyRepo *repository.CurrencyRepository,
+	exchangeRateRepo *repository.ExchangeRateRepository,
+	priceService *PriceService,
+) *ForecastService {
+	return &ForecastService{
+		subscriptionRepo: subscriptionRepo,
+		pauseWindowRepo:  pauseWindowRepo,
+		userRepo:         userRepo,
+		currencyRepo:     currencyRepo,
+		exchangeRateRepo: exchangeRateRepo,
+		priceService:     priceService,
+	}
+}
+
+// Forecast expands each live subscription's billing cycle forward from its
+// next billing date and totals the charges per month, payment method and
+// category. Each charge is priced at the price in effect on its date,
+// scheduled pauses push billing dates back, and paused subscriptions are
+// included from the date they resume. Billing dates that have already
+// passed without being renewed count towards the first month.
+func (s *ForecastService) Forecast(userID models.ULID, months int) (*Forecast, error) {
+	if months < 1 || months > MaxForecastMonths {
+		return nil, utils.NewValidationError("months", fmt.Sprintf("months must be between 1 and %d", MaxForecastMonths))
+	}
+
+	statuses := append([]models.SubscriptionStatus{models.SubscriptionStatusPaused}, models.LiveStatuses...)
+	subscriptions, err := s.subscriptionRepo.GetWithDetails(userID, statuses)
+	if err != nil {
+		return nil, err
+	}
+	pauses, err := s.openPauses(subscriptions)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := s.priceService.Timeline(subscriptions)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	forecast := &Forecast{
+		From:   now,
+		To:     start.AddDate(0, months, 0).Add(-time.Nanosecond),
+		Months: make([]ForecastMonth, months),
+	}
+
+	builders := make([]forecastMonthBuilder, months)
+	for i := range forecast.Months {
+		forecast.Months[i].Month = start.AddDate(0, i, 0).Format("2006-01")
+		builders[i] = newForecastMonthBuilder()
+	}
+
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		// Charges are added as they are projected, as a badly overdue
+		// subscription can have more of them than OccurrencesBetween expands.
+		subscription.EachOccurrence(subscription.NextBillingDate, forecast.To, func(date time.Time) bool {
+			utc := date.UTC()
+			index := (utc.Year()-start.Year())*12 + int(utc.Month()-start.Month())
+			if index < 0 {
+				index = 0
+			}
+			if index < months {
+				amount, currency := prices.At(subscription, date)
+				builders[index].add(subscription, amount, currency)
+			}
+			return true
+		}, pauses[subscription.ID]...)
+	}
+
+	for i := range forecast.Months {
+		month := &forecast.Months[i]
+		month.Totals = builders[i].totals.list()
+		month.ByPaymentMethod = builders[i].byPaymentMethod.list()
+		month.ByCategory = builders[i].byCategory.list()
+	}
+
+	if err := s.convert(forecast, userID); err != nil {
+		return nil, err
+	}
+	return forecast, nil
+}
+
+// openPauses loads the scheduled and running pause windows of subscriptions,
+// keyed by subscription ID.
+func (s *ForecastService) openPauses(subscriptions []models.Subscription) (map[models.ULID][]models.PauseWindow, error) {
+	byID := make(map[models.ULID][]models.PauseWindow)
+	if len(subscriptions) == 0 {
+		return byID, nil
+	}
+
+	ids := make([]models.ULID, len(subscriptions))
+	for i, subscription := range subscriptions {
+		ids[i] = subscription.ID
+	}
+	pauseWindows, err := s.pauseWindowRepo.GetOpenForSubscriptions(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, pauseWindow := range pauseWindows {
+		byID[pauseWindow.SubscriptionID] = append(byID[pauseWindow.SubscriptionID], pauseWindow)
+	}
+	return byID, nil
+}
+
+// convert adds each month's total in the user's base currency.
+func (s *ForecastService) convert(forecast *Forecast, userID models.ULID) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.BaseCurrencyID == nil {
+		return nil
+	}
+	baseCurrencyID := *user.BaseCurrencyID
+
+	baseCurrency, err := s.currencyRepo.GetByID(baseCurrencyID)
+	if err != nil {
+		return err
+	}
+	forecast.BaseCurrency = baseCurrency
+
+	rates, err := loadRateTable(s.exchangeRateRepo, time.Now())
+	if err != nil {
+		return err
+	}
+
+	missing := make(map[models.ULID]bool)
+	for i := range forecast.Months {
+		month := &forecast.Months[i]
+		var total float64
+		for _, currencyTotal := range month.Totals {
+			amount, _, ok := rates.convert(currencyTotal.Amount, currencyTotal.CurrencyID, baseCurrencyID)
+			if !ok {
+				if !missing[currencyTotal.CurrencyID] {
+					missing[currencyTotal.CurrencyID] = true
+					forecast.MissingRates = append(forecast.MissingRates, currencyTotal.CurrencyCode)
+				}
+				continue
+			}
+			total += amount
+		}
+		converted := roundCents(total)
+		month.ConvertedTotal = &converted
+	}
+	return nil
+}
+
+type forecastKey struct {
+	groupID, currencyID models.ULID
+}
+
+// forecastGroups accumulates charges per group and currency.
+type forecastGroups map[forecastKey]*ForecastTotal
+
+func (g forecastGroups) add(groupID models.ULID, groupName string, amount float64, currency models.Currency) {
+	key := forecastKey{groupID, currency.ID}
+	total, ok := g[key]
+	if !ok {
+		total = &ForecastTotal{
+			GroupID:      groupID,
+			GroupName:    groupName,
+			CurrencyID:   currency.ID,
+			CurrencyCode: currency.Code,
+		}
+		g[key] = total
+	}
+	total.Amount += amount
+	total.Charges++
+}
+
+// list returns the groups with the largest amounts first.
+func (g forecastGroups) list() []ForecastTotal {
+	totals := make([]ForecastTotal, 0, len(g))
+	for _, total := range g {
+		total.Amount = roundCents(total.Amount)
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Amount != totals[j].Amount {
+			return totals[i].Amount > totals[j].Amount
+		}
+		if totals[i].GroupName != totals[j].GroupName {
+			return totals[i].GroupName < totals[j].GroupName
+		}
+		return totals[i].CurrencyCode < totals[j].CurrencyCode
+	})
+	return totals
+}
+
+type forecastMonthBuilder struct {
+	totals, byPaymentMethod, byCategory forecastGroups
+}
+
+func newForecastMonthBuilder() forecastMonthBuilder {
+	return forecastMonthBuilder{
+		totals:          forecastGroups{},
+		byPaymentMethod: forecastGroups{},
+		byCategory:      forecastGroups{},
+	}
+}
+
+// add records one charge of subscription, of amount in currency.
+func (b forecastMonthBuilder) add(subscription *models.Subscription, amount float64, currency models.Currency) {
+	b.totals.add(currency.ID, currency.Code, amount, currency)
+	b.byPaymentMethod.add(subscription.PaymentMethodID, subscription.PaymentMethod.Name, amount, currency)
+	b.byCategory.add(subscription.CategoryID, subscription.Category.Name, amount, currency)
+}
@@ -2,30 +2,70 @@ package services
 
 import (
 	"fmt"
+	"sort"
 	"subscription-tracker/internal/models"
 	"subscription-tracker/internal/repository"
 	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type PaymentMethodService struct {
 	paymentMethodRepo *repository.PaymentMethodRepository
+	subscriptionRepo  *repository.SubscriptionRepository
 }
 
 type CreatePaymentMethodRequest struct {
-	Name     string                   `json:"name" binding:"required"`
-	Type     models.PaymentMethodType `json:"type" binding:"required"`
-	LastFour string                   `json:"lastFour,omitempty"`
+	Name        string                   `json:"name" binding:"required"`
+	Type        models.PaymentMethodType `json:"type" binding:"required"`
+	LastFour    string                   `json:"lastFour,omitempty"`
+	ExpiryMonth *int                     `json:"expiryMonth"` // Cards only
+	ExpiryYear  *int                     `json:"expiryYear"`
 }
 
 type UpdatePaymentMethodRequest struct {
-	Name     string                   `json:"name" binding:"required"`
-	Type     models.PaymentMethodType `json:"type" binding:"required"`
-	LastFour string                   `json:"lastFour" binding:"required,len=4"`
+	Name        string                   `json:"name" binding:"required"`
+	Type        models.PaymentMethodType `json:"type" binding:"required"`
+	LastFour    string                   `json:"lastFour" binding:"required,len=4"`
+	ExpiryMonth *int                     `json:"expiryMonth"` // Cards only
+	ExpiryYear  *int                     `json:"expiryYear"`
+}
+
+type MigratePaymentMethodRequest struct {
+	TargetPaymentMethodID string `json:"targetPaymentMethodId" binding:"required"`
+	// Limits the migration to these subscriptions; all subscriptions on the
+	// payment method are moved when empty.
+	SubscriptionIDs []string `json:"subscriptionIds"`
+	ArchiveSource   bool     `json:"archiveSource"`
+}
+
+// PaymentMethodMigration reports the outcome of a migration.
+type PaymentMethodMigration struct {
+	Source   models.PaymentMethod `json:"source"`
+	Target   models.PaymentMethod `json:"target"`
+	Migrated int64                `json:"migrated"`
+}
+
+// AtRiskSubscription is a subscription whose payment method expires before
+// it is next billed.
+type AtRiskSubscription struct {
+	SubscriptionID  models.ULID          `json:"subscriptionId"`
+	Name            string               `json:"name"`
+	NextBillingDate time.Time            `json:"nextBillingDate"`
+	Amount          float64              `json:"amount"`
+	Currency        models.Currency      `json:"currency"`
+	PaymentMethod   models.PaymentMethod `json:"paymentMethod"`
+	ExpiresAt       time.Time            `json:"expiresAt"`
 }
 
-func NewPaymentMethodService(paymentMethodRepo *repository.PaymentMethodRepository) *PaymentMethodService {
+func NewPaymentMethodService(
+	paymentMethodRepo *repository.PaymentMethodRepository,
+	subscriptionRepo *repository.SubscriptionRepository,
+) *PaymentMethodService {
 	return &PaymentMethodService{
 		paymentMethodRepo: paymentMethodRepo,
+		subscriptionRepo:  subscriptionRepo,
 	}
 }
 
@@ -33,6 +73,9 @@ func (s *PaymentMethodService) Create(req *CreatePaymentMethodRequest, userID mo
 	if !models.IsValidPaymentMethodType(req.Type) {
 		return nil, utils.NewValidationError("type", "invalid payment method type")
 	}
+	if err := validateExpiry(req.Type, req.ExpiryMonth, req.ExpiryYear); err != nil {
+		return nil, err
+	}
 
 	exists, err := s.paymentMethodRepo.ExistsByNameTypeAndUser(req.Name, req.Type, userID, nil)
 	if err != nil {
@@ -43,10 +86,12 @@ func (s *PaymentMethodService) Create(req *CreatePaymentMethodRequest, userID mo
 	}
 
 	paymentMethod := &models.PaymentMethod{
-		UserID:   userID,
-		Name:     req.Name,
-		Type:     req.Type,
-		LastFour: req.LastFour,
+		UserID:      userID,
+		Name:        req.Name,
+		Type:        req.Type,
+		LastFour:    req.LastFour,
+		ExpiryMonth: req.ExpiryMonth,
+		ExpiryYear:  req.ExpiryYear,
 	}
 
 	if err := s.paymentMethodRepo.Create(paymentMethod); err != nil {
@@ -56,14 +101,126 @@ func (s *PaymentMethodService) Create(req *CreatePaymentMethodRequest, userID mo
 	return paymentMethod, nil
 }
 
-func (s *PaymentMethodService) GetAll(userID models.ULID) ([]models.PaymentMethod, error) {
-	return s.paymentMethodRepo.GetAllForUser(userID)
+func (s *PaymentMethodService) GetAll(userID models.ULID, includeArchived bool) ([]models.PaymentMethod, error) {
+	return s.paymentMethodRepo.GetAllForUser(userID, includeArchived)
+}
+
+// Migrate moves subscriptions from one payment method to another in a single
+// transaction, e.g. when a card is replaced, and optionally archives the old
+// method.
+func (s *PaymentMethodService) Migrate(id models.ULID, req *MigratePaymentMethodRequest, userID models.ULID) (*PaymentMethodMigration, error) {
+	var targetID models.ULID
+	if err := targetID.UnmarshalJSON([]byte(`"` + req.TargetPaymentMethodID + `"`)); err != nil {
+		return nil, utils.NewValidationError("targetPaymentMethodId", "invalid format")
+	}
+	if targetID == id {
+		return nil, utils.NewValidationError("targetPaymentMethodId", "target must differ from the payment method being migrated")
+	}
+
+	source, err := s.paymentMethodRepo.GetByID(id)
+	if err != nil || source.UserID != userID {
+		return nil, utils.NewNotFoundError("payment method")
+	}
+	target, err := s.paymentMethodRepo.GetByID(targetID)
+	if err != nil {
+		return nil, utils.NewNotFoundError("target payment method")
+	}
+	if target.UserID != userID {
+		return nil, utils.NewForbiddenError("target payment method does not belong to user")
+	}
+	if target.ArchivedAt != nil {
+		return nil, utils.NewValidationError("targetPaymentMethodId", "target payment method is archived")
+	}
+
+	subscriptionIDs := make([]models.ULID, len(req.SubscriptionIDs))
+	for i, value := range req.SubscriptionIDs {
+		if err := subscriptionIDs[i].UnmarshalJSON([]byte(`"` + value + `"`)); err != nil {
+			return nil, utils.NewValidationError("subscriptionIds", fmt.Sprintf("invalid subscription ID '%s'", value))
+		}
+	}
+	if len(subscriptionIDs) > 0 {
+		subscriptions, err := s.subscriptionRepo.GetByPaymentMethod(id, userID)
+		if err != nil {
+			return nil, err
+		}
+		onSource := make(map[models.ULID]bool, len(subscriptions))
+		for _, subscription := range subscriptions {
+			onSource[subscription.ID] = true
+		}
+		for i, subscriptionID := range subscriptionIDs {
+			if !onSource[subscriptionID] {
+				return nil, utils.NewValidationError("subscriptionIds",
+					fmt.Sprintf("subscription '%s' is not billed to this payment method", req.SubscriptionIDs[i]))
+			}
+		}
+	}
+
+	migration := &PaymentMethodMigration{Target: *target}
+	err = s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		migrated, err := s.subscriptionRepo.WithTx(tx).ReassignPaymentMethod(userID, id, targetID, subscriptionIDs)
+		if err != nil {
+			return err
+		}
+		migration.Migrated = migrated
+
+		if req.ArchiveSource && source.ArchivedAt == nil {
+			now := time.Now()
+			source.ArchivedAt = &now
+			return s.paymentMethodRepo.WithTx(tx).Update(source)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	migration.Source = *source
+	return migration, nil
+}
+
+// AtRisk lists the user's live subscriptions billed to a card that expires
+// before their next billing date, soonest billing first, so the card can be
+// replaced before the renewal fails.
+func (s *PaymentMethodService) AtRisk(userID models.ULID) ([]AtRiskSubscription, error) {
+	subscriptions, err := s.subscriptionRepo.GetActiveWithDetails(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	atRisk := []AtRiskSubscription{}
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		expiresAt := subscription.PaymentMethod.ExpiresAt()
+		if expiresAt == nil || !subscription.BilledOn(subscription.NextBillingDate) {
+			continue
+		}
+		if subscription.NextBillingDate.Before(*expiresAt) {
+			continue
+		}
+		atRisk = append(atRisk, AtRiskSubscription{
+			SubscriptionID:  subscription.ID,
+			Name:            subscription.Name,
+			NextBillingDate: subscription.NextBillingDate,
+			Amount:          subscription.BilledAmount(),
+			Currency:        subscription.Currency,
+			PaymentMethod:   subscription.PaymentMethod,
+			ExpiresAt:       *expiresAt,
+		})
+	}
+
+	sort.SliceStable(atRisk, func(i, j int) bool {
+		return atRisk[i].NextBillingDate.Before(atRisk[j].NextBillingDate)
+	})
+	return atRisk, nil
 }
 
 func (s *PaymentMethodService) Update(id models.ULID, req *UpdatePaymentMethodRequest, userID models.ULID) (*models.PaymentMethod, error) {
 	if !models.IsValidPaymentMethodType(req.Type) {
 		return nil, fmt.Errorf("invalid payment method type: %s", req.Type)
 	}
+	if err := validateExpiry(req.Type, req.ExpiryMonth, req.ExpiryYear); err != nil {
+		return nil, err
+	}
 
 	paymentMethod, err := s.paymentMethodRepo.GetByID(id)
 	if err != nil {
@@ -85,6 +242,8 @@ func (s *PaymentMethodService) Update(id models.ULID, req *UpdatePaymentMethodRe
 	paymentMethod.Name = req.Name
 	paymentMethod.Type = req.Type
 	paymentMethod.LastFour = req.LastFour
+	paymentMethod.ExpiryMonth = req.ExpiryMonth
+	paymentMethod.ExpiryYear = req.ExpiryYear
 
 	if err := s.paymentMethodRepo.Update(paymentMethod); err != nil {
 		return nil, err
@@ -93,15 +252,80 @@ func (s *PaymentMethodService) Update(id models.ULID, req *UpdatePaymentMethodRe
 	return paymentMethod, nil
 }
 
-func (s *PaymentMethodService) Delete(id models.ULID, userID models.ULID) error {
+// Delete removes a payment method. A payment method still used by
+// subscriptions is only deleted when reassignTo names another payment method
+// to move them to; otherwise a conflict listing them is returned.
+func (s *PaymentMethodService) Delete(id models.ULID, userID models.ULID, reassignTo *models.ULID) error {
 	paymentMethod, err := s.paymentMethodRepo.GetByID(id)
 	if err != nil {
-		return fmt.Errorf("payment method not found")
+		return utils.NewNotFoundError("payment method")
 	}
 
 	if paymentMethod.UserID != userID {
-		return fmt.Errorf("payment method not found")
+		return utils.NewNotFoundError("payment method")
+	}
+
+	if reassignTo != nil {
+		if *reassignTo == id {
+			return utils.NewValidationError("reassignTo", "cannot reassign to the payment method being deleted")
+		}
+		target, err := s.paymentMethodRepo.GetByID(*reassignTo)
+		if err != nil || target.UserID != userID {
+			return utils.NewNotFoundError("reassignTo payment method")
+		}
 	}
 
-	return s.paymentMethodRepo.Delete(paymentMethod)
+	return s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		ids := []models.ULID{id}
+		if reassignTo != nil {
+			ids = append(ids, *reassignTo)
+		}
+		locked, err := s.paymentMethodRepo.WithTx(tx).LockByIDs(ids...)
+		if err != nil {
+			return err
+		}
+		if len(locked) != len(ids) {
+			return utils.NewNotFoundError("payment method")
+		}
+		for _, target := range locked {
+			if target.ID != id && target.ArchivedAt != nil {
+				return utils.NewValidationError("reassignTo", "reassignTo payment method is archived")
+			}
+		}
+
+		subscriptions, err := s.subscriptionRepo.WithTx(tx).GetByPaymentMethod(id, userID)
+		if err != nil {
+			return err
+		}
+		if len(subscriptions) > 0 {
+			if reassignTo == nil {
+				return inUseError("payment method", subscriptions)
+			}
+			if _, err := s.subscriptionRepo.WithTx(tx).ReassignPaymentMethod(userID, id, *reassignTo, nil); err != nil {
+				return err
+			}
+		}
+		return s.paymentMethodRepo.WithTx(tx).Delete(paymentMethod)
+	})
+}
+
+// validateExpiry checks that an expiry is only given for cards, with both
+// month and year.
+func validateExpiry(pmType models.PaymentMethodType, month, year *int) error {
+	if month == nil && year == nil {
+		return nil
+	}
+	if !models.IsCardPaymentMethodType(pmType) {
+		return utils.NewValidationError("expiryMonth", "only cards have an expiry date")
+	}
+	if month == nil || year == nil {
+		return utils.NewValidationError("expiryMonth", "expiryMonth and expiryYear must be set together")
+	}
+	if *month < 1 || *month > 12 {
+		return utils.NewValidationError("expiryMonth", "expiryMonth must be between 1 and 12")
+	}
+	if *year < 2000 || *year > 2099 {
+		return utils.NewValidationError("expiryYear", "expiryYear must be a four-digit year")
+	}
+	return nil
 }
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PaymentService struct {
+	paymentRepo       *repository.PaymentRepository
+	subscriptionRepo  *repository.SubscriptionRepository
+	currencyRepo      *repository.CurrencyRepository
+	paymentMethodRepo *repository.PaymentMethodRepository
+}
+
+type CreatePaymentRequest struct {
+	Amount          float64   `json:"amount" binding:"required,gt=0"`
+	PaidAt          time.Time `json:"paidAt" binding:"required"`
+	CurrencyID      string    `json:"currencyId"`      // Defaults to the subscription's currency
+	PaymentMethodID string    `json:"paymentMethodId"` // Defaults to the subscription's payment method
+	Notes           string    `json:"notes"`
+}
+
+type UpdatePaymentRequest struct {
+	Amount          float64   `json:"amount" binding:"required,gt=0"`
+	PaidAt          time.Time `json:"paidAt" binding:"required"`
+	CurrencyID      string    `json:"currencyId" binding:"required"`
+	PaymentMethodID string    `json:"paymentMethodId" binding:"required"`
+	Notes           string    `json:"notes"`
+}
+
+// PaymentHistory lists the charges of a subscription with lifetime totals.
+type PaymentHistory struct {
+	Payments []models.Payment          `json:"payments"`
+	Totals   []repository.PaymentTotal `json:"totals"`
+}
+
+func NewPaymentService(
+	paymentRepo *repository.PaymentRepository,
+	subscriptionRepo *repository.SubscriptionRepository,
+	currencyRepo *repository.CurrencyRepository,
+	paymentMethodRepo *repository.PaymentMethodRepository,
+) *PaymentService {
+	return &PaymentService{
+		paymentRepo:       paymentRepo,
+		subscriptionRepo:  subscriptionRepo,
+		currencyRepo:      currencyRepo,
+		paymentMethodRepo: paymentMethodRepo,
+	}
+}
+
+func (s *PaymentService) GetAll(subscriptionID, userID models.ULID) (*PaymentHistory, error) {
+	if _, err := s.getSubscription(subscriptionID, userID); err != nil {
+		return nil, err
+	}
+
+	payments, err := s.paymentRepo.GetAllForSubscription(subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := s.paymentRepo.TotalsForSubscription(subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaymentHistory{
+		Payments: payments,
+		Totals:   totals,
+	}, nil
+}
+
+func (s *PaymentService) Create(subscriptionID models.ULID, req *CreatePaymentRequest, userID models.ULID) (*models.Payment, error) {
+	subscription, err := s.getSubscription(subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	currencyID := subscription.CurrencyID
+	if req.CurrencyID != "" {
+		if err := currencyID.UnmarshalJSON([]byte(`"` + req.CurrencyID + `"`)); err != nil {
+			return nil, utils.NewValidationError("currencyId", "invalid format")
+		}
+	}
+	paymentMethodID := subscription.PaymentMethodID
+	if req.PaymentMethodID != "" {
+		if err := paymentMethodID.UnmarshalJSON([]byte(`"` + req.PaymentMethodID + `"`)); err != nil {
+			return nil, utils.NewValidationError("paymentMethodId", "invalid format")
+		}
+	}
+
+	if err := s.validateReferences(currencyID, paymentMethodID, userID); err != nil {
+		return nil, err
+	}
+
+	payment := &models.Payment{
+		UserID:          userID,
+		SubscriptionID:  subscription.ID,
+		PaymentMethodID: paymentMethodID,
+		CurrencyID:      currencyID,
+		Amount:          req.Amount,
+		PaidAt:          req.PaidAt,
+		Source:          models.PaymentSourceManual,
+		Notes:           req.Notes,
+	}
+
+	if err := s.paymentRepo.Create(payment); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+func (s *PaymentService) Update(subscriptionID, id models.ULID, req *UpdatePaymentRequest, userID models.ULID) (*models.Payment, error) {
+	payment, err := s.getPayment(subscriptionID, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var currencyID, paymentMethodID models.ULID
+	if err := currencyID.UnmarshalJSON([]byte(`"` + req.CurrencyID + `"`)); err != nil {
+		return nil, utils.NewValidationError("currencyId", "invalid format")
+	}
+	if err := paymentMethodID.UnmarshalJSON([]byte(`"` + req.PaymentMethodID + `"`)); err != nil {
+		return nil, utils.NewValidationError("paymentMethodId", "invalid format")
+	}
+
+	// Corrections may keep pointing at a payment method that has since been
+	// removed, so only check ownership when it changes.
+	if paymentMethodID != payment.PaymentMethodID || currencyID != payment.CurrencyID {
+		if err := s.validateReferences(currencyID, paymentMethodID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	payment.Amount = req.Amount
+	payment.PaidAt = req.PaidAt
+	payment.CurrencyID = currencyID
+	payment.PaymentMethodID = paymentMethodID
+	payment.Notes = req.Notes
+
+	if err := s.paymentRepo.Update(payment); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+func (s *PaymentService) Delete(subscriptionID, id models.ULID, userID models.ULID) error {
+	payment, err := s.getPayment(subscriptionID, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.paymentRepo.Delete(payment)
+}
+
+func (s *PaymentService) getSubscription(id, userID models.ULID) (*models.Subscription, error) {
+	subscription, err := s.subscriptionRepo.GetByID(id, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewNotFoundError("subscription")
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *PaymentService) getPayment(subscriptionID, id, userID models.ULID) (*models.Payment, error) {
+	payment, err := s.paymentRepo.GetByID(id, subscriptionID, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewNotFoundError("payment")
+		}
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (s *PaymentService) validateReferences(currencyID, paymentMethodID, userID models.ULID) error {
+	paymentMethod, err := s.paymentMethodRepo.GetByID(paymentMethodID)
+	if err != nil {
+		return utils.NewNotFoundError("payment method")
+	}
+	if paymentMethod.UserID != userID {
+		return utils.NewForbiddenError("payment method does not belong to user")
+	}
+
+	if _, err := s.currencyRepo.GetByID(currencyID); err != nil {
+		return utils.NewNotFoundError("currency")
+	}
+
+	return nil
+}
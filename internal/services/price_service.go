@@ -0,0 +1,257 @@
+package services
+
+import (
+	"sort"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const priceChangeBatchSize = 100
+
+type PriceService struct {
+	priceChangeRepo  *repository.PriceChangeRepository
+	subscriptionRepo *repository.SubscriptionRepository
+	currencyRepo     *repository.CurrencyRepository
+}
+
+type SchedulePriceChangeRequest struct {
+	Amount        float64   `json:"amount" binding:"required,gt=0"`
+	CurrencyID    string    `json:"currencyId"` // Defaults to the subscription's currency
+	EffectiveDate time.Time `json:"effectiveDate" binding:"required"`
+	Note          string    `json:"note"`
+}
+
+// PriceInEffect is the price of a subscription on a given date.
+type PriceInEffect struct {
+	Date          time.Time        `json:"date"`
+	Amount        float64          `json:"amount"`
+	CurrencyID    models.ULID      `json:"currencyId"`
+	Currency      *models.Currency `json:"currency,omitempty"`
+	EffectiveDate *time.Time       `json:"effectiveDate,omitempty"` // Start of the price, if known from history
+	PriceChangeID *models.ULID     `json:"priceChangeId,omitempty"`
+}
+
+func NewPriceService(
+	priceChangeRepo *repository.PriceChangeRepository,
+	subscriptionRepo *repository.SubscriptionRepository,
+	currencyRepo *repository.CurrencyRepository,
+) *PriceService {
+	return &PriceService{
+		priceChangeRepo:  priceChangeRepo,
+		subscriptionRepo: subscriptionRepo,
+		currencyRepo:     currencyRepo,
+	}
+}
+
+func (s *PriceService) GetHistory(subscriptionID, userID models.ULID) ([]models.PriceChange, error) {
+	if _, err := s.getSubscription(subscriptionID, userID); err != nil {
+		return nil, err
+	}
+	return s.priceChangeRepo.GetAllForSubscription(subscriptionID, userID)
+}
+
+// Schedule records a future price change that is applied automatically once
+// its effective date arrives.
+func (s *PriceService) Schedule(subscriptionID models.ULID, req *SchedulePriceChangeRequest, userID models.ULID) (*models.PriceChange, error) {
+	subscription, err := s.getSubscription(subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.EffectiveDate.After(time.Now()) {
+		return nil, utils.NewValidationError("effectiveDate", "effectiveDate must be in the future")
+	}
+
+	currencyID := subscription.CurrencyID
+	if req.CurrencyID != "" {
+		if err := currencyID.UnmarshalJSON([]byte(`"` + req.CurrencyID + `"`)); err != nil {
+			return nil, utils.NewValidationError("currencyId", "invalid format")
+		}
+		if _, err := s.currencyRepo.GetByID(currencyID); err != nil {
+			return nil, utils.NewNotFoundError("currency")
+		}
+	}
+
+	priceChange := &models.PriceChange{
+		UserID:         userID,
+		SubscriptionID: subscription.ID,
+		Amount:         req.Amount,
+		CurrencyID:     currencyID,
+		EffectiveDate:  req.EffectiveDate,
+		Note:           req.Note,
+	}
+	if err := s.priceChangeRepo.Create(priceChange); err != nil {
+		return nil, err
+	}
+
+	return priceChange, nil
+}
+
+// CancelScheduled removes a price change that has not been applied yet.
+func (s *PriceService) CancelScheduled(subscriptionID, id, userID models.ULID) error {
+	priceChange, err := s.priceChangeRepo.GetByID(id, subscriptionID, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.NewNotFoundError("price change")
+		}
+		return err
+	}
+
+	if !priceChange.IsScheduled() {
+		return utils.NewValidationError("id", "price change has already been applied")
+	}
+
+	return s.priceChangeRepo.Delete(priceChange)
+}
+
+// EffectiveAt returns the price of the subscription on date, taking scheduled
+// changes into account. Subscriptions without history report their current
+// price.
+func (s *PriceService) EffectiveAt(subscriptionID, userID models.ULID, date time.Time) (*PriceInEffect, error) {
+	subscription, err := s.getSubscription(subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	priceChange, err := s.priceChangeRepo.GetEffectiveAt(subscription.ID, date)
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+		return &PriceInEffect{
+			Date:       date,
+			Amount:     subscription.Amount,
+			CurrencyID: subscription.CurrencyID,
+		}, nil
+	}
+
+	return &PriceInEffect{
+		Date:          date,
+		Amount:        priceChange.Amount,
+		CurrencyID:    priceChange.CurrencyID,
+		Currency:      &priceChange.Currency,
+		EffectiveDate: &priceChange.EffectiveDate,
+		PriceChangeID: &priceChange.ID,
+	}, nil
+}
+
+// PriceTimeline holds the price history of several subscriptions, to look up
+// their prices on many dates without a query per date.
+type PriceTimeline struct {
+	changes map[models.ULID][]models.PriceChange // Earliest first
+}
+
+// Timeline loads the price history of subscriptions, including scheduled
+// changes.
+func (s *PriceService) Timeline(subscriptions []models.Subscription) (*PriceTimeline, error) {
+	timeline := &PriceTimeline{changes: make(map[models.ULID][]models.PriceChange)}
+	if len(subscriptions) == 0 {
+		return timeline, nil
+	}
+
+	ids := make([]models.ULID, len(subscriptions))
+	for i, subscription := range subscriptions {
+		ids[i] = subscription.ID
+	}
+	priceChanges, err := s.priceChangeRepo.GetForSubscriptions(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, priceChange := range priceChanges {
+		timeline.changes[priceChange.SubscriptionID] = append(timeline.changes[priceChange.SubscriptionID], priceChange)
+	}
+	return timeline, nil
+}
+
+// At returns the amount the subscription is charged on date and its
+// currency, like EffectiveAt. A trialing subscription is charged its
+// post-trial price unless a change takes effect after the trial ends.
+// Subscription.Currency must be loaded.
+func (t *PriceTimeline) At(subscription *models.Subscription, date time.Time) (float64, models.Currency) {
+	changes := t.changes[subscription.ID]
+	i := sort.Search(len(changes), func(i int) bool {
+		return changes[i].EffectiveDate.After(date)
+	})
+	if i == 0 {
+		return subscription.BilledAmount(), subscription.Currency
+	}
+
+	priceChange := &changes[i-1]
+	if subscription.IsTrialing() && subscription.TrialEndDate != nil && priceChange.EffectiveDate.Before(*subscription.TrialEndDate) {
+		return subscription.BilledAmount(), subscription.Currency
+	}
+	return priceChange.Amount, priceChange.Currency
+}
+
+// ApplyDue copies every scheduled price change whose effective date has
+// arrived onto its subscription. It returns the number of changes applied.
+func (s *PriceService) ApplyDue(now time.Time) (int, error) {
+	applied := 0
+	for {
+		var batch int
+		err := s.priceChangeRepo.Transaction(func(tx *gorm.DB) error {
+			priceChangeRepo := s.priceChangeRepo.WithTx(tx)
+			subscriptionRepo := s.subscriptionRepo.WithTx(tx)
+
+			priceChanges, err := priceChangeRepo.LockDue(now, priceChangeBatchSize)
+			if err != nil {
+				return err
+			}
+			batch = len(priceChanges)
+
+			for i := range priceChanges {
+				priceChange := &priceChanges[i]
+				subscription := &models.Subscription{
+					ID:         priceChange.SubscriptionID,
+					Amount:     priceChange.Amount,
+					CurrencyID: priceChange.CurrencyID,
+				}
+				if err := subscriptionRepo.UpdateFields(subscription, "amount", "currency_id"); err != nil {
+					return err
+				}
+				if err := priceChangeRepo.MarkApplied(priceChange, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return applied, err
+		}
+
+		applied += batch
+		if batch < priceChangeBatchSize {
+			return applied, nil
+		}
+	}
+}
+
+func (s *PriceService) getSubscription(id, userID models.ULID) (*models.Subscription, error) {
+	subscription, err := s.subscriptionRepo.GetByID(id, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewNotFoundError("subscription")
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// appliedPriceChange records the subscription's current price as already in
+// effect from effectiveDate.
+func appliedPriceChange(subscription *models.Subscription, effectiveDate time.Time, note string) *models.PriceChange {
+	appliedAt := time.Now()
+	return &models.PriceChange{
+		UserID:         subscription.UserID,
+		SubscriptionID: subscription.ID,
+		Amount:         subscription.Amount,
+		CurrencyID:     subscription.CurrencyID,
+		EffectiveDate:  effectiveDate,
+		AppliedAt:      &appliedAt,
+		Note:           note,
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/notify"
+	"subscription-tracker/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// reminderMaxAttempts bounds delivery retries for a single reminder.
+	reminderMaxAttempts = 5
+	reminderBatchSize   = 50
+	// reminderRetryDelay is the wait after the first failed delivery; it
+	// doubles with every further failure.
+	reminderRetryDelay = 5 * time.Minute
+)
+
+type ReminderService struct {
+	reminderRepo     *repository.ReminderRepository
+	subscriptionRepo *repository.SubscriptionRepository
+	userRepo         *repository.UserRepository
+	notifier         notify.Notifier
+}
+
+func NewReminderService(
+	reminderRepo *repository.ReminderRepository,
+	subscriptionRepo *repository.SubscriptionRepository,
+	userRepo *repository.UserRepository,
+	notifier notify.Notifier,
+) *ReminderService {
+	return &ReminderService{
+		reminderRepo:     reminderRepo,
+		subscriptionRepo: subscriptionRepo,
+		userRepo:         userRepo,
+		notifier:         notifier,
+	}
+}
+
+// Schedule creates a reminder for every subscription whose reminder window
+// has opened. Occurrences that already have a reminder are skipped.
+func (s *ReminderService) Schedule(now time.Time) (int, error) {
+	subscriptions, err := s.subscriptionRepo.GetDueForReminder(now)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, subscription := range subscriptions {
+		reminder := &models.Reminder{
+			UserID:         subscription.UserID,
+			SubscriptionID: subscription.ID,
+			BillingDate:    subscription.NextBillingDate,
+			RemindAt:       subscription.NextBillingDate.AddDate(0, 0, -subscription.ReminderDays),
+		}
+		inserted, err := s.reminderRepo.CreateIfAbsent(reminder)
+		if err != nil {
+			return created, err
+		}
+		if inserted {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// Dispatch delivers pending reminders through the notifier. Each reminder is
+// locked while it is sent so concurrent workers never deliver it twice. Failed
+// deliveries are retried with exponential backoff, up to reminderMaxAttempts
+// times.
+func (s *ReminderService) Dispatch(ctx context.Context, now time.Time) (int, error) {
+	sent := 0
+	for {
+		var batch int
+		err := s.reminderRepo.Transaction(func(tx *gorm.DB) error {
+			reminderRepo := s.reminderRepo.WithTx(tx)
+
+			reminders, err := reminderRepo.LockPending(now, reminderMaxAttempts, reminderBatchSize)
+			if err != nil {
+				return err
+			}
+			batch = len(reminders)
+
+			for i := range reminders {
+				reminder := &reminders[i]
+				if err := s.deliver(ctx, reminder); err != nil {
+					log.Printf("Reminder %s failed: %v", reminder.ID, err)
+					reminder.Attempts++
+					reminder.LastError = err.Error()
+					// Failed reminders wait out their backoff, so they are not
+					// selected again by the next batch of this run.
+					nextAttemptAt := now.Add(reminderRetryDelay << (reminder.Attempts - 1))
+					reminder.NextAttemptAt = &nextAttemptAt
+				} else {
+					deliveredAt := time.Now()
+					reminder.Attempts++
+					reminder.SentAt = &deliveredAt
+					reminder.LastError = ""
+					sent++
+				}
+				if err := reminderRepo.Update(reminder); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return sent, err
+		}
+		if batch < reminderBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+func (s *ReminderService) deliver(ctx context.Context, reminder *models.Reminder) error {
+	subscription, err := s.subscriptionRepo.GetByIDWithDetails(reminder.SubscriptionID)
+	if err != nil {
+		return fmt.Errorf("load subscription: %w", err)
+	}
+	user, err := s.userRepo.GetByID(reminder.UserID)
+	if err != nil {
+		return fmt.Errorf("load user: %w", err)
+	}
+
+	return s.notifier.Notify(ctx, reminderMessage(user, subscription, reminder.BillingDate))
+}
+
+func reminderMessage(user *models.User, subscription *models.Subscription, billingDate time.Time) notify.Message {
+	date := billingDate.Format("January 2, 2006")
+	amount := strconv.FormatFloat(subscription.Amount, 'f', 2, 64) + " " + subscription.Currency.Code
+
+	if subscription.IsTrialing() && subscription.PostTrialAmount != nil {
+		price := strconv.FormatFloat(*subscription.PostTrialAmount, 'f', 2, 64) + " " + subscription.Currency.Code
+		return notify.Message{
+			To:      user.Email,
+			Subject: fmt.Sprintf("Your %s trial ends on %s", subscription.Name, date),
+			Body: fmt.Sprintf("Hi %s,\n\nYour free trial of %s ends on %s. Unless you cancel before then, "+
+				"it converts to a paid plan at %s.\n", user.Name, subscription.Name, date, price),
+		}
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\n%s renews on %s for %s", user.Name, subscription.Name, date, amount)
+	if subscription.PaymentMethod.Name != "" {
+		body += " using " + subscription.PaymentMethod.Name
+	}
+	body += ".\n\nIf you no longer need it, now is a good time to cancel.\n"
+
+	return notify.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("%s renews on %s", subscription.Name, date),
+		Body:    body,
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// renewalBatchSize limits how many subscriptions are locked per transaction.
+const renewalBatchSize = 100
+
+type RenewalService struct {
+	subscriptionRepo *repository.SubscriptionRepository
+	billingCycleRepo *repository.BillingCycleRepository
+	paymentRepo      *repository.PaymentRepository
+	priceChangeRepo  *repository.PriceChangeRepository
+}
+
+func NewRenewalService(
+	subscriptionRepo *repository.SubscriptionRepository,
+	billingCycleRepo *repository.BillingCycleRepository,
+	paymentRepo *repository.PaymentRepository,
+	priceChangeRepo *repository.PriceChangeRepository,
+) *RenewalService {
+	return &RenewalService{
+		subscriptionRepo: subscriptionRepo,
+		billingCycleRepo: billingCycleRepo,
+		paymentRepo:      paymentRepo,
+		priceChangeRepo:  priceChangeRepo,
+	}
+}
+
+// RenewDue rolls NextBillingDate forward for every renewable subscription whose
+// billing date has passed, catching up on all missed periods and recording a
+// payment for each of them. Periods starting on or after a pending
+// cancellation are not billed. It returns the number of subscriptions renewed.
+func (s *RenewalService) RenewDue(now time.Time) (int, error) {
+	renewed := 0
+	for {
+		var batch int
+		err := s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+			subscriptionRepo := s.subscriptionRepo.WithTx(tx)
+			paymentRepo := s.paymentRepo.WithTx(tx)
+			priceChangeRepo := s.priceChangeRepo.WithTx(tx)
+
+			subscriptions, err := subscriptionRepo.LockDueForRenewal(now, renewalBatchSize)
+			if err != nil {
+				return err
+			}
+			batch = len(subscriptions)
+
+			billingCycles, err := billingCyclesFor(s.billingCycleRepo, subscriptions)
+			if err != nil {
+				return err
+			}
+
+			for i := range subscriptions {
+				subscription := &subscriptions[i]
+				billingCycle := billingCycles[subscription.BillingCycleID]
+				for !subscription.NextBillingDate.After(now) && subscription.BilledOn(subscription.NextBillingDate) {
+					payment, err := renewalPayment(priceChangeRepo, subscription)
+					if err != nil {
+						return err
+					}
+					if err := paymentRepo.Create(payment); err != nil {
+						return err
+					}
+					subscription.NextBillingDate = billingCycle.NextDate(subscription.NextBillingDate, subscription.AnchorDay())
+				}
+				if err := subscriptionRepo.UpdateNextBillingDate(subscription); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return renewed, err
+		}
+
+		renewed += batch
+		if batch < renewalBatchSize {
+			return renewed, nil
+		}
+	}
+}
+
+// billingCyclesFor loads the billing cycles referenced by subscriptions,
+// keyed by ID. Soft-deleted cycles are included.
+func billingCyclesFor(billingCycleRepo *repository.BillingCycleRepository, subscriptions []models.Subscription) (map[models.ULID]models.BillingCycle, error) {
+	ids := make([]models.ULID, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		ids = append(ids, subscription.BillingCycleID)
+	}
+
+	billingCycles, err := billingCycleRepo.GetByIDsUnscoped(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[models.ULID]models.BillingCycle, len(billingCycles))
+	for _, billingCycle := range billingCycles {
+		byID[billingCycle.ID] = billingCycle
+	}
+	return byID, nil
+}
+
+// renewalPayment builds the payment for the subscription's current billing
+// date, charged at the price in effect on that date.
+func renewalPayment(priceChangeRepo *repository.PriceChangeRepository, subscription *models.Subscription) (*models.Payment, error) {
+	payment := &models.Payment{
+		UserID:          subscription.UserID,
+		SubscriptionID:  subscription.ID,
+		PaymentMethodID: subscription.PaymentMethodID,
+		CurrencyID:      subscription.CurrencyID,
+		Amount:          subscription.Amount,
+		PaidAt:          subscription.NextBillingDate,
+		Source:          models.PaymentSourceRenewal,
+	}
+
+	priceChange, err := priceChangeRepo.GetEffectiveAt(subscription.ID, subscription.NextBillingDate)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return payment, nil
+		}
+		return nil, err
+	}
+	payment.Amount = priceChange.Amount
+	payment.CurrencyID = priceChange.CurrencyID
+	return payment, nil
+}
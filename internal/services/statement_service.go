@@ -0,0 +1,311 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/statements"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type StatementService struct {
+	subscriptionRepo  *repository.SubscriptionRepository
+	categoryRepo      *repository.CategoryRepository
+	currencyRepo      *repository.CurrencyRepository
+	billingCycleRepo  *repository.BillingCycleRepository
+	paymentMethodRepo *repository.PaymentMethodRepository
+	priceChangeRepo   *repository.PriceChangeRepository
+}
+
+// StatementImportRequest describes an uploaded statement.
+type StatementImportRequest struct {
+	Format statements.Format
+	// Mapping describes the columns of a CSV statement.
+	Mapping statements.CSVMapping
+	// Card or account the statement belongs to. When empty it is matched
+	// by the last four digits of the account number in OFX files.
+	PaymentMethodID string
+	CategoryID      string // Category of the drafts, changed by the user before confirming
+	// Currency code of transactions that do not state one. Defaults to the
+	// account currency of OFX files.
+	Currency string
+}
+
+// StatementImport reports the recurring charges found in a statement and the
+// draft subscriptions proposed for them.
+type StatementImport struct {
+	Transactions  int                  `json:"transactions"`
+	Charges       []DetectedCharge     `json:"charges"`
+	Drafts        int                  `json:"drafts"`
+	PaymentMethod models.PaymentMethod `json:"paymentMethod"`
+}
+
+// DetectedCharge is a recurring charge and the draft created for it, or why
+// none was.
+type DetectedCharge struct {
+	statements.RecurringCharge
+	Draft      *models.Subscription `json:"draft,omitempty"`
+	SkipReason string               `json:"skipReason,omitempty"`
+}
+
+func NewStatementService(
+	subscriptionRepo *repository.SubscriptionRepository,
+	categoryRepo *repository.CategoryRepository,
+	currencyRepo *repository.CurrencyRepository,
+	billingCycleRepo *repository.BillingCycleRepository,
+	paymentMethodRepo *repository.PaymentMethodRepository,
+	priceChangeRepo *repository.PriceChangeRepository,
+) *StatementService {
+	return &StatementService{
+		subscriptionRepo:  subscriptionRepo,
+		categoryRepo:      categoryRepo,
+		currencyRepo:      currencyRepo,
+		billingCycleRepo:  billingCycleRepo,
+		paymentMethodRepo: paymentMethodRepo,
+		priceChangeRepo:   priceChangeRepo,
+	}
+}
+
+// Import parses a bank or card statement, detects the merchants charging a
+// similar amount at a regular interval and creates a draft subscription for
+// each, with the billing cycle, amount and currency inferred from the
+// charges. Charges matching the name of an existing subscription are
+// skipped, so importing overlapping statements does not duplicate drafts.
+// Drafts are not billed or reminded about until the user confirms them.
+func (s *StatementService) Import(req *StatementImportRequest, r io.Reader, userID models.ULID) (*StatementImport, error) {
+	if !statements.IsValidFormat(req.Format) {
+		return nil, utils.NewValidationError("format", "format must be csv, ofx or qfx")
+	}
+
+	var categoryID models.ULID
+	if err := categoryID.UnmarshalJSON([]byte(`"` + req.CategoryID + `"`)); err != nil {
+		return nil, utils.NewValidationError("categoryId", "invalid format")
+	}
+	category, err := s.categoryRepo.GetByID(categoryID)
+	if err != nil {
+		return nil, utils.NewNotFoundError("category")
+	}
+	if !category.SystemDefined && (category.UserID == nil || *category.UserID != userID) {
+		return nil, utils.NewForbiddenError("category does not belong to user")
+	}
+
+	statement, err := statements.Parse(req.Format, r, req.Mapping)
+	if err != nil {
+		return nil, utils.NewValidationError("file", err.Error())
+	}
+
+	paymentMethod, err := s.statementPaymentMethod(req.PaymentMethodID, statement.AccountID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	currencies, err := s.currencyRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	currencyIDs := make(map[string]models.ULID, len(currencies))
+	for _, currency := range currencies {
+		currencyIDs[currency.Code] = currency.ID
+	}
+	defaultCurrency := strings.ToUpper(req.Currency)
+	if defaultCurrency == "" {
+		defaultCurrency = statement.Currency
+	}
+	if defaultCurrency != "" {
+		if _, ok := currencyIDs[defaultCurrency]; !ok {
+			return nil, utils.NewValidationError("currency", fmt.Sprintf("unknown currency '%s'", defaultCurrency))
+		}
+	}
+
+	existing, err := s.subscriptionRepo.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+	existingNames := make(map[string]string, len(existing))
+	for _, subscription := range existing {
+		existingNames[strings.ToLower(subscription.Name)] = subscription.Name
+	}
+
+	billingCycles, err := s.billingCycleRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StatementImport{
+		Transactions:  len(statement.Transactions),
+		Charges:       []DetectedCharge{},
+		PaymentMethod: *paymentMethod,
+	}
+	now := time.Now()
+
+	err = s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		subscriptionRepo := s.subscriptionRepo.WithTx(tx)
+		priceChangeRepo := s.priceChangeRepo.WithTx(tx)
+		billingCycleRepo := s.billingCycleRepo.WithTx(tx)
+
+		// The category and payment method are locked and re-read as
+		// SubscriptionService.validateReferences does, in case one has been
+		// deleted since it was resolved.
+		if _, err := s.categoryRepo.WithTx(tx).LockForShare(category.ID); err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return utils.NewNotFoundError("category")
+			}
+			return err
+		}
+		locked, err := s.paymentMethodRepo.WithTx(tx).LockForShare(paymentMethod.ID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return utils.NewNotFoundError("payment method")
+			}
+			return err
+		}
+		if locked.ArchivedAt != nil {
+			return utils.NewValidationError("paymentMethodId", "payment method is archived")
+		}
+
+		for _, charge := range statements.Detect(statement.Transactions, defaultCurrency) {
+			detected := DetectedCharge{RecurringCharge: charge}
+
+			currencyID, knownCurrency := currencyIDs[charge.Currency]
+			if name, ok := existingNames[strings.ToLower(charge.Merchant)]; ok {
+				detected.SkipReason = fmt.Sprintf("matches existing subscription '%s'", name)
+			} else if charge.Currency == "" {
+				detected.SkipReason = "currency unknown; set the statement currency"
+			} else if !knownCurrency {
+				detected.SkipReason = fmt.Sprintf("unsupported currency '%s'", charge.Currency)
+			}
+			if detected.SkipReason != "" {
+				result.Charges = append(result.Charges, detected)
+				continue
+			}
+
+			billingCycle, err := matchBillingCycle(billingCycleRepo, &billingCycles, charge, userID)
+			if err != nil {
+				return err
+			}
+
+			subscription := &models.Subscription{
+				UserID:           userID,
+				Name:             charge.Merchant,
+				Description:      "Detected from statement: " + charge.Description,
+				Amount:           charge.Amount,
+				CategoryID:       category.ID,
+				CurrencyID:       currencyID,
+				BillingCycleID:   billingCycle.ID,
+				PaymentMethodID:  paymentMethod.ID,
+				BillingAnchorDay: charge.LastDate.Day(),
+				Status:           models.SubscriptionStatusDraft,
+			}
+			subscription.NextBillingDate = billingCycle.NextDate(charge.LastDate, subscription.BillingAnchorDay)
+			for subscription.NextBillingDate.Before(now) {
+				subscription.NextBillingDate = billingCycle.NextDate(subscription.NextBillingDate, subscription.BillingAnchorDay)
+			}
+
+			if err := subscriptionRepo.Create(subscription); err != nil {
+				return err
+			}
+			if err := priceChangeRepo.Create(appliedPriceChange(subscription, subscription.CreatedAt, "")); err != nil {
+				return err
+			}
+
+			detected.Draft = subscription
+			result.Charges = append(result.Charges, detected)
+			result.Drafts++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// statementPaymentMethod resolves the payment method given in the request,
+// or else the one whose last four digits match the statement's account.
+func (s *StatementService) statementPaymentMethod(value, accountID string, userID models.ULID) (*models.PaymentMethod, error) {
+	if value != "" {
+		var paymentMethodID models.ULID
+		if err := paymentMethodID.UnmarshalJSON([]byte(`"` + value + `"`)); err != nil {
+			return nil, utils.NewValidationError("paymentMethodId", "invalid format")
+		}
+		paymentMethod, err := s.paymentMethodRepo.GetByID(paymentMethodID)
+		if err != nil {
+			return nil, utils.NewNotFoundError("payment method")
+		}
+		if paymentMethod.UserID != userID {
+			return nil, utils.NewForbiddenError("payment method does not belong to user")
+		}
+		if paymentMethod.ArchivedAt != nil {
+			return nil, utils.NewValidationError("paymentMethodId", "payment method is archived")
+		}
+		return paymentMethod, nil
+	}
+
+	if len(accountID) >= 4 {
+		lastFour := accountID[len(accountID)-4:]
+		paymentMethods, err := s.paymentMethodRepo.GetAllForUser(userID, false)
+		if err != nil {
+			return nil, err
+		}
+		var match *models.PaymentMethod
+		for i := range paymentMethods {
+			if paymentMethods[i].LastFour != lastFour {
+				continue
+			}
+			if match != nil {
+				return nil, utils.NewValidationError("paymentMethodId",
+					fmt.Sprintf("several payment methods end in %s; choose one", lastFour))
+			}
+			match = &paymentMethods[i]
+		}
+		if match != nil {
+			return match, nil
+		}
+	}
+
+	return nil, utils.NewValidationError("paymentMethodId", "paymentMethodId is required when the statement does not identify a known card or account")
+}
+
+// matchBillingCycle finds the user's or a system billing cycle with the
+// charge's interval, creating a custom one if there is none, e.g. "Every 2
+// weeks". Created cycles are added to billingCycles for later charges. A
+// matched cycle is locked like the other references of the drafts.
+func matchBillingCycle(
+	billingCycleRepo *repository.BillingCycleRepository,
+	billingCycles *[]models.BillingCycle,
+	charge statements.RecurringCharge,
+	userID models.ULID,
+) (*models.BillingCycle, error) {
+	unit := models.BillingCycleUnit(charge.Unit)
+	for i := range *billingCycles {
+		billingCycle := &(*billingCycles)[i]
+		if billingCycle.Unit != unit || billingCycle.Interval != charge.Interval {
+			continue
+		}
+		if _, err := billingCycleRepo.LockForShare(billingCycle.ID); err != nil {
+			if err == gorm.ErrRecordNotFound {
+				continue // Deleted since the import began
+			}
+			return nil, err
+		}
+		return billingCycle, nil
+	}
+
+	billingCycle := models.BillingCycle{
+		Name:     fmt.Sprintf("Every %d %ss", charge.Interval, charge.Unit),
+		Interval: charge.Interval,
+		Unit:     unit,
+		UserID:   &userID,
+	}
+	if err := billingCycleRepo.Create(&billingCycle); err != nil {
+		return nil, err
+	}
+	*billingCycles = append(*billingCycles, billingCycle)
+	return &(*billingCycles)[len(*billingCycles)-1], nil
+}
@@ -1,7 +1,10 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"sort"
 	"subscription-tracker/internal/models"
 	"subscription-tracker/internal/repository"
 	"time"
@@ -17,39 +20,100 @@ type SubscriptionService struct {
 	currencyRepo      *repository.CurrencyRepository
 	billingCycleRepo  *repository.BillingCycleRepository
 	paymentMethodRepo *repository.PaymentMethodRepository
+	priceChangeRepo   *repository.PriceChangeRepository
+	cancellationRepo  *repository.CancellationRepository
+	pauseWindowRepo   *repository.PauseWindowRepository
+	userRepo          *repository.UserRepository
+	exchangeRateRepo  *repository.ExchangeRateRepository
+	budgetRepo        *repository.BudgetRepository
+	analyticsRepo     *repository.AnalyticsRepository
 }
 
 type CreateSubscriptionRequest struct {
-	Name            string    `json:"name" binding:"required"`
-	Description     string    `json:"description"`
-	Amount          float64   `json:"amount" binding:"required,gt=0"`
-	CategoryID      string    `json:"categoryId" binding:"required"`
-	CurrencyID      string    `json:"currencyId" binding:"required"`
-	BillingCycleID  string    `json:"billingCycleId" binding:"required"`
-	PaymentMethodID string    `json:"paymentMethodId" binding:"required"`
-	NextBillingDate time.Time `json:"nextBillingDate" binding:"required"`
-	ReminderDays    int       `json:"reminderDays" binding:"gte=0"`
+	Name            string     `json:"name" binding:"required"`
+	Description     string     `json:"description"`
+	Amount          float64    `json:"amount" binding:"gte=0"` // May be 0 during a free trial
+	CategoryID      string     `json:"categoryId" binding:"required"`
+	CurrencyID      string     `json:"currencyId" binding:"required"`
+	BillingCycleID  string     `json:"billingCycleId" binding:"required"`
+	PaymentMethodID string     `json:"paymentMethodId" binding:"required"`
+	NextBillingDate time.Time  `json:"nextBillingDate"` // Required unless trialEndDate is set
+	ReminderDays    int        `json:"reminderDays" binding:"gte=0"`
+	TrialStartDate  *time.Time `json:"trialStartDate"`
+	TrialEndDate    *time.Time `json:"trialEndDate"`
+	PostTrialAmount *float64   `json:"postTrialAmount"`
 }
 
 type UpdateSubscriptionRequest struct {
 	Name            string    `json:"name" binding:"required"`
 	Description     string    `json:"description"`
-	Amount          float64   `json:"amount" binding:"required,gt=0"`
+	Amount          float64   `json:"amount" binding:"gte=0"` // May be 0 during a free trial
 	CategoryID      string    `json:"categoryId" binding:"required"`
 	CurrencyID      string    `json:"currencyId" binding:"required"`
 	BillingCycleID  string    `json:"billingCycleId" binding:"required"`
 	PaymentMethodID string    `json:"paymentMethodId" binding:"required"`
-	NextBillingDate time.Time `json:"nextBillingDate" binding:"required"`
+	NextBillingDate time.Time `json:"nextBillingDate"` // Required unless trialEndDate is set
 	ReminderDays    int       `json:"reminderDays" binding:"gte=0"`
-	Active          bool      `json:"active"`
+	// Trial fields are ignored once the trial has converted
+	TrialStartDate  *time.Time `json:"trialStartDate"`
+	TrialEndDate    *time.Time `json:"trialEndDate"`
+	PostTrialAmount *float64   `json:"postTrialAmount"`
 }
 
+type PauseSubscriptionRequest struct {
+	StartDate *time.Time `json:"startDate"` // Defaults to now
+	EndDate   *time.Time `json:"endDate"`   // Omit to pause until resumed
+}
+
+// SubscriptionList is a list of subscriptions converted to the user's base
+// currency. Without a base currency only Subscriptions is set.
+type SubscriptionList struct {
+	Subscriptions []ConvertedSubscription `json:"subscriptions"`
+	BaseCurrency  *models.Currency        `json:"baseCurrency,omitempty"`
+	// Normalized cost of the live subscriptions in the list, in the base
+	// currency. Subscriptions without an exchange rate are left out.
+	MonthlyTotal *float64 `json:"monthlyTotal,omitempty"`
+	YearlyTotal  *float64 `json:"yearlyTotal,omitempty"`
+	MissingRates []string `json:"missingRates,omitempty"` // Codes of currencies that could not be converted
+}
+
+// ConvertedSubscription is a subscription with its amount in the user's
+// base currency and the date of the exchange rate used.
+type ConvertedSubscription struct {
+	models.Subscription
+	ConvertedAmount *float64   `json:"convertedAmount,omitempty"`
+	RateDate        *time.Time `json:"rateDate,omitempty"`
+}
+
+// UpcomingRenewal is a single projected billing date of a subscription.
+type UpcomingRenewal struct {
+	SubscriptionID models.ULID          `json:"subscriptionId"`
+	Name           string               `json:"name"`
+	Date           time.Time            `json:"date"`
+	Amount         float64              `json:"amount"`
+	Currency       models.Currency      `json:"currency"`
+	PaymentMethod  models.PaymentMethod `json:"paymentMethod"`
+	Category       models.Category      `json:"category"`
+}
+
+// MaxProjectionWindow is the longest date range renewals are projected over.
+const MaxProjectionWindow = 2 * 366 * 24 * time.Hour
+
+const pauseBatchSize = 100
+
 func NewSubscriptionService(
 	subscriptionRepo *repository.SubscriptionRepository,
 	categoryRepo *repository.CategoryRepository,
 	currencyRepo *repository.CurrencyRepository,
 	billingCycleRepo *repository.BillingCycleRepository,
 	paymentMethodRepo *repository.PaymentMethodRepository,
+	priceChangeRepo *repository.PriceChangeRepository,
+	cancellationRepo *repository.CancellationRepository,
+	pauseWindowRepo *repository.PauseWindowRepository,
+	userRepo *repository.UserRepository,
+	exchangeRateRepo *repository.ExchangeRateRepository,
+	budgetRepo *repository.BudgetRepository,
+	analyticsRepo *repository.AnalyticsRepository,
 ) *SubscriptionService {
 	return &SubscriptionService{
 		subscriptionRepo:  subscriptionRepo,
@@ -57,15 +121,34 @@ func NewSubscriptionService(
 		currencyRepo:      currencyRepo,
 		billingCycleRepo:  billingCycleRepo,
 		paymentMethodRepo: paymentMethodRepo,
+		priceChangeRepo:   priceChangeRepo,
+		cancellationRepo:  cancellationRepo,
+		pauseWindowRepo:   pauseWindowRepo,
+		userRepo:          userRepo,
+		exchangeRateRepo:  exchangeRateRepo,
+		budgetRepo:        budgetRepo,
+		analyticsRepo:     analyticsRepo,
 	}
 }
 
+// validateReferences checks that the referenced rows exist and belong to the
+// user. An archived payment method is only accepted if it is
+// currentPaymentMethodID, the one the subscription is already billed to.
+//
+// It must run in the transaction that writes the subscription: the rows are
+// locked FOR SHARE until it ends. Deleting a category, billing cycle or
+// payment method only soft-deletes it, so the foreign key alone would let a
+// subscription be saved against a deleted row; with the share lock the
+// write either completes before the delete locks the row and is moved with
+// the others, or waits for the delete and then finds the row gone.
 func (s *SubscriptionService) validateReferences(
+	tx *gorm.DB,
 	categoryID, currencyID, billingCycleID, paymentMethodID models.ULID,
+	currentPaymentMethodID models.ULID,
 	userID models.ULID,
 ) error {
 	// Validate category
-	category, err := s.categoryRepo.GetByID(categoryID)
+	category, err := s.categoryRepo.WithTx(tx).LockForShare(categoryID)
 	if err != nil {
 		return utils.NewNotFoundError("category")
 	}
@@ -74,7 +157,7 @@ func (s *SubscriptionService) validateReferences(
 	}
 
 	// Validate billing cycle
-	billingCycle, err := s.billingCycleRepo.GetByID(billingCycleID)
+	billingCycle, err := s.billingCycleRepo.WithTx(tx).LockForShare(billingCycleID)
 	if err != nil {
 		return utils.NewNotFoundError("billing cycle")
 	}
@@ -83,13 +166,16 @@ func (s *SubscriptionService) validateReferences(
 	}
 
 	// Validate payment method
-	paymentMethod, err := s.paymentMethodRepo.GetByID(paymentMethodID)
+	paymentMethod, err := s.paymentMethodRepo.WithTx(tx).LockForShare(paymentMethodID)
 	if err != nil {
 		return utils.NewNotFoundError("payment method")
 	}
 	if paymentMethod.UserID != userID {
 		return utils.NewForbiddenError("payment method does not belong to user")
 	}
+	if paymentMethod.ArchivedAt != nil && paymentMethodID != currentPaymentMethodID {
+		return utils.NewValidationError("paymentMethodId", "payment method is archived")
+	}
 
 	// Validate currency (just check existence since currencies are system-wide)
 	_, err = s.currencyRepo.GetByID(currencyID)
@@ -100,49 +186,124 @@ func (s *SubscriptionService) validateReferences(
 	return nil
 }
 
-func (s *SubscriptionService) Create(req *CreateSubscriptionRequest, userID models.ULID) (*models.Subscription, error) {
+// Create adds a subscription. The returned warnings report a category budget
+// the subscription takes past its limit.
+func (s *SubscriptionService) Create(req *CreateSubscriptionRequest, userID models.ULID) (*models.Subscription, []utils.Warning, error) {
 	// Parse IDs
 	var categoryID, currencyID, billingCycleID, paymentMethodID models.ULID
 	if err := categoryID.UnmarshalJSON([]byte(`"` + req.CategoryID + `"`)); err != nil {
-		return nil, utils.NewValidationError("categoryId", "invalid format")
+		return nil, nil, utils.NewValidationError("categoryId", "invalid format")
 	}
 	if err := currencyID.UnmarshalJSON([]byte(`"` + req.CurrencyID + `"`)); err != nil {
-		return nil, utils.NewValidationError("currencyId", "invalid format")
+		return nil, nil, utils.NewValidationError("currencyId", "invalid format")
 	}
 	if err := billingCycleID.UnmarshalJSON([]byte(`"` + req.BillingCycleID + `"`)); err != nil {
-		return nil, utils.NewValidationError("billingCycleId", "invalid format")
+		return nil, nil, utils.NewValidationError("billingCycleId", "invalid format")
 	}
 	if err := paymentMethodID.UnmarshalJSON([]byte(`"` + req.PaymentMethodID + `"`)); err != nil {
-		return nil, utils.NewValidationError("paymentMethodId", "invalid format")
+		return nil, nil, utils.NewValidationError("paymentMethodId", "invalid format")
+	}
+
+	subscription := &models.Subscription{
+		UserID:           userID,
+		Name:             req.Name,
+		Description:      req.Description,
+		Amount:           req.Amount,
+		CategoryID:       categoryID,
+		CurrencyID:       currencyID,
+		BillingCycleID:   billingCycleID,
+		PaymentMethodID:  paymentMethodID,
+		NextBillingDate:  req.NextBillingDate,
+		BillingAnchorDay: req.NextBillingDate.Day(),
+		ReminderDays:     req.ReminderDays,
+		Status:           models.SubscriptionStatusActive,
+	}
+
+	if err := applyTrial(subscription, req.TrialStartDate, req.TrialEndDate, req.PostTrialAmount); err != nil {
+		return nil, nil, err
+	}
+	if err := validateBilling(subscription); err != nil {
+		return nil, nil, err
+	}
+
+	budgetBefore := s.categoryBudget(subscription.CategoryID, userID)
+	err := s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		if err := s.validateReferences(tx, categoryID, currencyID, billingCycleID, paymentMethodID, models.ULID{}, userID); err != nil {
+			return err
+		}
+		if err := s.subscriptionRepo.WithTx(tx).Create(subscription); err != nil {
+			return err
+		}
+		return s.priceChangeRepo.WithTx(tx).Create(appliedPriceChange(subscription, subscription.CreatedAt, ""))
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err := s.validateReferences(categoryID, currencyID, billingCycleID, paymentMethodID, userID); err != nil {
+	return subscription, s.budgetWarnings(subscription, budgetBefore), nil
+}
+
+// GetAll returns the user's subscriptions, optionally limited to statuses,
+// converted to their base currency.
+func (s *SubscriptionService) GetAll(userID models.ULID, statuses ...models.SubscriptionStatus) (*SubscriptionList, error) {
+	for _, status := range statuses {
+		if !models.IsValidSubscriptionStatus(status) {
+			return nil, utils.NewValidationError("status", fmt.Sprintf("invalid status '%s'", status))
+		}
+	}
+	subscriptions, err := s.subscriptionRepo.GetAll(userID, statuses...)
+	if err != nil {
 		return nil, err
 	}
+	return s.convert(subscriptions, userID)
+}
 
-	subscription := &models.Subscription{
-		UserID:          userID,
-		Name:            req.Name,
-		Description:     req.Description,
-		Amount:          req.Amount,
-		CategoryID:      categoryID,
-		CurrencyID:      currencyID,
-		BillingCycleID:  billingCycleID,
-		PaymentMethodID: paymentMethodID,
-		NextBillingDate: req.NextBillingDate,
-		ReminderDays:    req.ReminderDays,
-		Active:          true,
+// Upcoming expands every active subscription's billing cycle into the
+// concrete billing dates that fall within [from, to], ordered by date.
+func (s *SubscriptionService) Upcoming(userID models.ULID, from, to time.Time) ([]UpcomingRenewal, error) {
+	if to.Before(from) {
+		return nil, utils.NewValidationError("to", "to must not be before from")
+	}
+	if to.Sub(from) > MaxProjectionWindow {
+		return nil, utils.NewValidationError("to", "date range must not exceed two years")
 	}
 
-	if err := s.subscriptionRepo.Create(subscription); err != nil {
+	subscriptions, err := s.subscriptionRepo.GetActiveWithDetails(userID)
+	if err != nil {
 		return nil, err
 	}
 
-	return subscription, nil
-}
+	renewals := []UpcomingRenewal{}
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		dates, err := subscription.OccurrencesBetween(from, to)
+		if err != nil {
+			if errors.Is(err, models.ErrTooManyOccurrences) {
+				return nil, utils.NewValidationError("to", "date range covers too many billing dates")
+			}
+			return nil, err
+		}
+		for _, date := range dates {
+			renewals = append(renewals, UpcomingRenewal{
+				SubscriptionID: subscription.ID,
+				Name:           subscription.Name,
+				Date:           date,
+				Amount:         subscription.BilledAmount(),
+				Currency:       subscription.Currency,
+				PaymentMethod:  subscription.PaymentMethod,
+				Category:       subscription.Category,
+			})
+		}
+	}
+
+	sort.SliceStable(renewals, func(i, j int) bool {
+		if renewals[i].Date.Equal(renewals[j].Date) {
+			return renewals[i].Name < renewals[j].Name
+		}
+		return renewals[i].Date.Before(renewals[j].Date)
+	})
 
-func (s *SubscriptionService) GetAll(userID models.ULID) ([]models.Subscription, error) {
-	return s.subscriptionRepo.GetAll(userID)
+	return renewals, nil
 }
 
 func (s *SubscriptionService) GetByID(id, userID models.ULID) (*models.Subscription, error) {
@@ -156,46 +317,129 @@ func (s *SubscriptionService) GetByID(id, userID models.ULID) (*models.Subscript
 	return subscription, nil
 }
 
-func (s *SubscriptionService) GetByCategory(categoryID, userID models.ULID) ([]models.Subscription, error) {
-	return s.subscriptionRepo.GetByCategory(categoryID, userID)
+func (s *SubscriptionService) GetByCategory(categoryID, userID models.ULID) (*SubscriptionList, error) {
+	subscriptions, err := s.subscriptionRepo.GetByCategory(categoryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.convert(subscriptions, userID)
 }
 
-func (s *SubscriptionService) GetByBillingCycle(billingCycleID, userID models.ULID) ([]models.Subscription, error) {
-	return s.subscriptionRepo.GetByBillingCycle(billingCycleID, userID)
+func (s *SubscriptionService) GetByBillingCycle(billingCycleID, userID models.ULID) (*SubscriptionList, error) {
+	subscriptions, err := s.subscriptionRepo.GetByBillingCycle(billingCycleID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.convert(subscriptions, userID)
 }
 
-func (s *SubscriptionService) GetByPaymentMethod(paymentMethodID, userID models.ULID) ([]models.Subscription, error) {
-	return s.subscriptionRepo.GetByPaymentMethod(paymentMethodID, userID)
+func (s *SubscriptionService) GetByPaymentMethod(paymentMethodID, userID models.ULID) (*SubscriptionList, error) {
+	subscriptions, err := s.subscriptionRepo.GetByPaymentMethod(paymentMethodID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.convert(subscriptions, userID)
 }
 
-func (s *SubscriptionService) Update(id models.ULID, req *UpdateSubscriptionRequest, userID models.ULID) (*models.Subscription, error) {
+// convert converts subscriptions to the user's base currency using the
+// latest exchange rates, and totals the normalized cost of the live ones.
+func (s *SubscriptionService) convert(subscriptions []models.Subscription, userID models.ULID) (*SubscriptionList, error) {
+	list := &SubscriptionList{Subscriptions: make([]ConvertedSubscription, len(subscriptions))}
+	for i := range subscriptions {
+		list.Subscriptions[i].Subscription = subscriptions[i]
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.BaseCurrencyID == nil {
+		return list, nil
+	}
+	baseCurrencyID := *user.BaseCurrencyID
+
+	currencies, err := s.currencyRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	currencyCodes := make(map[models.ULID]string, len(currencies))
+	for i := range currencies {
+		currencyCodes[currencies[i].ID] = currencies[i].Code
+		if currencies[i].ID == baseCurrencyID {
+			list.BaseCurrency = &currencies[i]
+		}
+	}
+
+	rates, err := loadRateTable(s.exchangeRateRepo, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	billingCycles, err := billingCyclesFor(s.billingCycleRepo, subscriptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var monthly float64
+	missing := make(map[models.ULID]bool)
+	for i := range list.Subscriptions {
+		subscription := &list.Subscriptions[i]
+		amount, rateDate, ok := rates.convert(subscription.Amount, subscription.CurrencyID, baseCurrencyID)
+		if !ok {
+			if !missing[subscription.CurrencyID] {
+				missing[subscription.CurrencyID] = true
+				list.MissingRates = append(list.MissingRates, currencyCodes[subscription.CurrencyID])
+			}
+			continue
+		}
+
+		converted := roundCents(amount)
+		subscription.ConvertedAmount = &converted
+		if !rateDate.IsZero() {
+			subscription.RateDate = &rateDate
+		}
+
+		if subscription.Status.IsLive() {
+			billed, _, _ := rates.convert(subscription.BilledAmount(), subscription.CurrencyID, baseCurrencyID)
+			billingCycle := billingCycles[subscription.BillingCycleID]
+			monthly += billed * billingCycle.MonthlyFactor()
+		}
+	}
+
+	monthlyTotal, yearlyTotal := roundCents(monthly), roundCents(monthly*12)
+	list.MonthlyTotal = &monthlyTotal
+	list.YearlyTotal = &yearlyTotal
+	return list, nil
+}
+
+// Update changes a subscription. Like Create, it warns when the change takes
+// the subscription's category over budget.
+func (s *SubscriptionService) Update(id models.ULID, req *UpdateSubscriptionRequest, userID models.ULID) (*models.Subscription, []utils.Warning, error) {
 	// Get existing subscription
 	subscription, err := s.subscriptionRepo.GetByID(id, userID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("subscription not found")
+			return nil, nil, fmt.Errorf("subscription not found")
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Parse IDs
 	var categoryID, currencyID, billingCycleID, paymentMethodID models.ULID
 	if err := categoryID.UnmarshalJSON([]byte(`"` + req.CategoryID + `"`)); err != nil {
-		return nil, fmt.Errorf("invalid category ID")
+		return nil, nil, fmt.Errorf("invalid category ID")
 	}
 	if err := currencyID.UnmarshalJSON([]byte(`"` + req.CurrencyID + `"`)); err != nil {
-		return nil, fmt.Errorf("invalid currency ID")
+		return nil, nil, fmt.Errorf("invalid currency ID")
 	}
 	if err := billingCycleID.UnmarshalJSON([]byte(`"` + req.BillingCycleID + `"`)); err != nil {
-		return nil, fmt.Errorf("invalid billing cycle ID")
+		return nil, nil, fmt.Errorf("invalid billing cycle ID")
 	}
 	if err := paymentMethodID.UnmarshalJSON([]byte(`"` + req.PaymentMethodID + `"`)); err != nil {
-		return nil, fmt.Errorf("invalid payment method ID")
+		return nil, nil, fmt.Errorf("invalid payment method ID")
 	}
 
-	if err := s.validateReferences(categoryID, currencyID, billingCycleID, paymentMethodID, userID); err != nil {
-		return nil, err
-	}
+	previousAmount, previousCurrencyID := subscription.Amount, subscription.CurrencyID
+	previousPaymentMethodID := subscription.PaymentMethodID
 
 	subscription.Name = req.Name
 	subscription.Description = req.Description
@@ -204,17 +448,331 @@ func (s *SubscriptionService) Update(id models.ULID, req *UpdateSubscriptionRequ
 	subscription.CurrencyID = currencyID
 	subscription.BillingCycleID = billingCycleID
 	subscription.PaymentMethodID = paymentMethodID
-	subscription.NextBillingDate = req.NextBillingDate
+	if !subscription.NextBillingDate.Equal(req.NextBillingDate) {
+		subscription.NextBillingDate = req.NextBillingDate
+		subscription.BillingAnchorDay = req.NextBillingDate.Day()
+	}
 	subscription.ReminderDays = req.ReminderDays
-	subscription.Active = req.Active
 
-	if err := s.subscriptionRepo.Update(subscription); err != nil {
+	if subscription.IsTrialing() {
+		if err := applyTrial(subscription, req.TrialStartDate, req.TrialEndDate, req.PostTrialAmount); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := validateBilling(subscription); err != nil {
+		return nil, nil, err
+	}
+
+	budgetBefore := s.categoryBudget(subscription.CategoryID, userID)
+	err = s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		if err := s.validateReferences(tx, categoryID, currencyID, billingCycleID, paymentMethodID, previousPaymentMethodID, userID); err != nil {
+			return err
+		}
+		if err := s.subscriptionRepo.WithTx(tx).Update(subscription); err != nil {
+			return err
+		}
+		if subscription.Amount == previousAmount && subscription.CurrencyID == previousCurrencyID {
+			return nil
+		}
+
+		// Subscriptions created before price history was kept get their
+		// previous price recorded first, so it still applies to earlier dates.
+		priceChangeRepo := s.priceChangeRepo.WithTx(tx)
+		hasHistory, err := priceChangeRepo.HasHistory(subscription.ID)
+		if err != nil {
+			return err
+		}
+		if !hasHistory {
+			previous := *subscription
+			previous.Amount = previousAmount
+			previous.CurrencyID = previousCurrencyID
+			if err := priceChangeRepo.Create(appliedPriceChange(&previous, subscription.CreatedAt, "")); err != nil {
+				return err
+			}
+		}
+		return priceChangeRepo.Create(appliedPriceChange(subscription, time.Now(), ""))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return subscription, s.budgetWarnings(subscription, budgetBefore), nil
+}
+
+// Pause schedules a pause window. A window starting now (or with no start
+// date) pauses the subscription right away; later windows are started by
+// ApplyPauses. Only one window may be open at a time.
+func (s *SubscriptionService) Pause(id models.ULID, req *PauseSubscriptionRequest, userID models.ULID) (*models.PauseWindow, error) {
+	subscription, err := s.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	startDate := now
+	if req.StartDate != nil && req.StartDate.After(now) {
+		startDate = *req.StartDate
+	}
+	if req.EndDate != nil && !req.EndDate.After(startDate) {
+		return nil, utils.NewValidationError("endDate", "endDate must be after startDate")
+	}
+
+	if !subscription.Status.CanTransitionTo(models.SubscriptionStatusPaused) {
+		return nil, utils.NewValidationError("status",
+			fmt.Sprintf("cannot change a %s subscription to %s", subscription.Status, models.SubscriptionStatusPaused))
+	}
+	if _, err := s.pauseWindowRepo.GetOpen(subscription.ID); err == nil {
+		return nil, utils.NewValidationError("startDate", "subscription already has an open pause")
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	pauseWindow := &models.PauseWindow{
+		UserID:         userID,
+		SubscriptionID: subscription.ID,
+		StartDate:      startDate,
+		EndDate:        req.EndDate,
+	}
+	if startDate.After(now) {
+		if err := s.pauseWindowRepo.Create(pauseWindow); err != nil {
+			return nil, err
+		}
+		return pauseWindow, nil
+	}
+
+	pauseWindow.StartedAt = &now
+	subscription.Status = models.SubscriptionStatusPaused
+	err = s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		if err := s.pauseWindowRepo.WithTx(tx).Create(pauseWindow); err != nil {
+			return err
+		}
+		return s.subscriptionRepo.WithTx(tx).UpdateFields(subscription, "status")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pauseWindow, nil
+}
+
+// Resume makes a paused, past-due or pending-cancellation subscription
+// active again. Resuming a paused subscription closes its pause window and
+// pushes the next billing date back by the time spent paused. Resuming
+// before a cancellation takes effect withdraws it.
+func (s *SubscriptionService) Resume(id, userID models.ULID) (*models.Subscription, error) {
+	subscription, err := s.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.Status == models.SubscriptionStatusDraft {
+		return nil, utils.NewValidationError("status", "drafts are activated by confirming them")
+	}
+	if !subscription.Status.CanTransitionTo(models.SubscriptionStatusActive) {
+		return nil, utils.NewValidationError("status",
+			fmt.Sprintf("cannot change a %s subscription to %s", subscription.Status, models.SubscriptionStatusActive))
+	}
+
+	now := time.Now()
+	err = s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		subscriptionRepo := s.subscriptionRepo.WithTx(tx)
+
+		switch subscription.Status {
+		case models.SubscriptionStatusPaused:
+			pauseWindowRepo := s.pauseWindowRepo.WithTx(tx)
+			pauseWindow, err := pauseWindowRepo.GetOpen(subscription.ID)
+			if err == gorm.ErrRecordNotFound {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			return resumeFromPause(subscriptionRepo, pauseWindowRepo, subscription, pauseWindow, now)
+		case models.SubscriptionStatusPendingCancellation:
+			subscription.Status = models.SubscriptionStatusActive
+			subscription.CancelsAt = nil
+			if err := s.cancellationRepo.WithTx(tx).WithdrawAll(subscription.ID, now); err != nil {
+				return err
+			}
+			return subscriptionRepo.UpdateFields(subscription, "status", "cancels_at")
+		}
+
+		subscription.Status = models.SubscriptionStatusActive
+		return subscriptionRepo.UpdateFields(subscription, "status")
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return subscription, nil
 }
 
+// Confirm activates a draft proposed by a statement import. A next billing
+// date that has passed since the import is moved forward by whole billing
+// cycles, without recording payments for the skipped dates. Like Create, it
+// warns when the subscription takes its category over budget.
+func (s *SubscriptionService) Confirm(id, userID models.ULID) (*models.Subscription, []utils.Warning, error) {
+	subscription, err := s.GetByID(id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if subscription.Status != models.SubscriptionStatusDraft {
+		return nil, nil, utils.NewValidationError("status", "only drafts can be confirmed")
+	}
+
+	billingCycle, err := s.billingCycleRepo.GetByID(subscription.BillingCycleID)
+	if err != nil {
+		return nil, nil, utils.NewNotFoundError("billing cycle")
+	}
+	now := time.Now()
+	for subscription.NextBillingDate.Before(now) {
+		subscription.NextBillingDate = billingCycle.NextDate(subscription.NextBillingDate, subscription.AnchorDay())
+	}
+
+	budgetBefore := s.categoryBudget(subscription.CategoryID, userID)
+	subscription.Status = models.SubscriptionStatusActive
+	subscription.BillingAnchorDay = subscription.AnchorDay()
+	if err := s.subscriptionRepo.UpdateFields(subscription, "status", "next_billing_date", "billing_anchor_day"); err != nil {
+		return nil, nil, err
+	}
+
+	return subscription, s.budgetWarnings(subscription, budgetBefore), nil
+}
+
+func (s *SubscriptionService) GetPauses(id, userID models.ULID) ([]models.PauseWindow, error) {
+	if _, err := s.GetByID(id, userID); err != nil {
+		return nil, err
+	}
+	return s.pauseWindowRepo.GetAllForSubscription(id, userID)
+}
+
+// CancelPause removes a pause window that has not started yet.
+func (s *SubscriptionService) CancelPause(subscriptionID, id, userID models.ULID) error {
+	pauseWindow, err := s.pauseWindowRepo.GetByID(id, subscriptionID, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.NewNotFoundError("pause")
+		}
+		return err
+	}
+
+	if !pauseWindow.IsScheduled() {
+		return utils.NewValidationError("id", "pause has already started")
+	}
+
+	return s.pauseWindowRepo.Delete(pauseWindow)
+}
+
+// ApplyPauses starts scheduled pause windows whose start date has arrived
+// and resumes subscriptions whose pause window has ended. Windows that can
+// no longer start, e.g. because the subscription was cancelled or deleted
+// meanwhile, are closed without effect. It returns the number of windows processed.
+func (s *SubscriptionService) ApplyPauses(now time.Time) (int, error) {
+	processed := 0
+	for {
+		var batch int
+		err := s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+			subscriptionRepo := s.subscriptionRepo.WithTx(tx)
+			pauseWindowRepo := s.pauseWindowRepo.WithTx(tx)
+
+			starting, err := pauseWindowRepo.LockDueToStart(now, pauseBatchSize)
+			if err != nil {
+				return err
+			}
+			for i := range starting {
+				pauseWindow := &starting[i]
+				subscription, err := subscriptionRepo.GetByID(pauseWindow.SubscriptionID, pauseWindow.UserID)
+				if err != nil && err != gorm.ErrRecordNotFound {
+					return err
+				}
+				if err == gorm.ErrRecordNotFound || !subscription.Status.CanTransitionTo(models.SubscriptionStatusPaused) {
+					pauseWindow.ResumedAt = &now
+					if err := pauseWindowRepo.Update(pauseWindow); err != nil {
+						return err
+					}
+					continue
+				}
+				pauseWindow.StartedAt = &now
+				subscription.Status = models.SubscriptionStatusPaused
+				if err := pauseWindowRepo.Update(pauseWindow); err != nil {
+					return err
+				}
+				if err := subscriptionRepo.UpdateFields(subscription, "status"); err != nil {
+					return err
+				}
+			}
+
+			ending, err := pauseWindowRepo.LockDueToEnd(now, pauseBatchSize)
+			if err != nil {
+				return err
+			}
+			for i := range ending {
+				pauseWindow := &ending[i]
+				subscription, err := subscriptionRepo.GetByID(pauseWindow.SubscriptionID, pauseWindow.UserID)
+				if err != nil && err != gorm.ErrRecordNotFound {
+					return err
+				}
+				if err == gorm.ErrRecordNotFound || subscription.Status != models.SubscriptionStatusPaused {
+					pauseWindow.ResumedAt = &now
+					if err := pauseWindowRepo.Update(pauseWindow); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := resumeFromPause(subscriptionRepo, pauseWindowRepo, subscription, pauseWindow, *pauseWindow.EndDate); err != nil {
+					return err
+				}
+			}
+
+			batch = max(len(starting), len(ending))
+			return nil
+		})
+		if err != nil {
+			return processed, err
+		}
+
+		processed += batch
+		if batch < pauseBatchSize {
+			return processed, nil
+		}
+	}
+}
+
+// categoryBudget returns the status of the budget of a category, or nil when
+// the category has no budget. Budgets only produce warnings, so failures are
+// logged rather than returned.
+func (s *SubscriptionService) categoryBudget(categoryID, userID models.ULID) *BudgetStatus {
+	budget, err := s.budgetRepo.GetByCategory(categoryID, userID)
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			log.Printf("Budget check for category %s failed: %v", categoryID, err)
+		}
+		return nil
+	}
+
+	statuses, err := budgetStatuses(s.analyticsRepo, s.exchangeRateRepo, []models.Budget{*budget}, userID)
+	if err != nil {
+		log.Printf("Budget check for category %s failed: %v", categoryID, err)
+		return nil
+	}
+	return &statuses[0]
+}
+
+// budgetWarnings warns when saving a live subscription took its category
+// over budget. before is the category's budget status from before the save;
+// a category that was already over budget is not reported again.
+func (s *SubscriptionService) budgetWarnings(subscription *models.Subscription, before *BudgetStatus) []utils.Warning {
+	if !subscription.Status.IsLive() || (before != nil && before.OverBudget) {
+		return nil
+	}
+
+	after := s.categoryBudget(subscription.CategoryID, subscription.UserID)
+	if after == nil || !after.OverBudget {
+		return nil
+	}
+	return []utils.Warning{overBudgetWarning(after)}
+}
+
 func (s *SubscriptionService) Delete(id models.ULID, userID models.ULID) error {
 	subscription, err := s.subscriptionRepo.GetByID(id, userID)
 	if err != nil {
@@ -224,5 +782,109 @@ func (s *SubscriptionService) Delete(id models.ULID, userID models.ULID) error {
 		return err
 	}
 
-	return s.subscriptionRepo.Delete(subscription)
+	// Open pause windows are closed with the subscription; otherwise the
+	// pause worker would keep picking them up without finding their
+	// subscription.
+	return s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		if err := s.pauseWindowRepo.WithTx(tx).CloseAll(subscription.ID, time.Now()); err != nil {
+			return err
+		}
+		return s.subscriptionRepo.WithTx(tx).Delete(subscription)
+	})
+}
+
+// applyTrial validates trial fields and sets them on the subscription. A
+// trialing subscription is next billed when its trial ends. Passing a nil
+// trialEnd removes the trial and makes the subscription active.
+func applyTrial(subscription *models.Subscription, trialStart, trialEnd *time.Time, postTrialAmount *float64) error {
+	if trialEnd == nil {
+		subscription.TrialStartDate = nil
+		subscription.TrialEndDate = nil
+		subscription.PostTrialAmount = nil
+		if subscription.IsTrialing() {
+			subscription.Status = models.SubscriptionStatusActive
+		}
+		return nil
+	}
+
+	if postTrialAmount == nil || *postTrialAmount <= 0 {
+		return utils.NewValidationError("postTrialAmount", "postTrialAmount must be greater than 0 for a trial")
+	}
+
+	start := time.Now()
+	if trialStart != nil {
+		start = *trialStart
+	} else if subscription.TrialStartDate != nil {
+		start = *subscription.TrialStartDate
+	}
+	if !trialEnd.After(start) {
+		return utils.NewValidationError("trialEndDate", "trialEndDate must be after trialStartDate")
+	}
+
+	subscription.TrialStartDate = &start
+	subscription.TrialEndDate = trialEnd
+	subscription.PostTrialAmount = postTrialAmount
+	subscription.NextBillingDate = *trialEnd
+	subscription.BillingAnchorDay = trialEnd.Day()
+	subscription.Status = models.SubscriptionStatusTrialing
+	return nil
+}
+
+// validateBilling checks the fields that only trials may leave empty.
+func validateBilling(subscription *models.Subscription) error {
+	if subscription.IsTrialing() {
+		return nil
+	}
+	if subscription.Amount <= 0 {
+		return utils.NewValidationError("amount", "amount must be greater than 0")
+	}
+	if subscription.NextBillingDate.IsZero() {
+		return utils.NewValidationError("nextBillingDate", "nextBillingDate is required")
+	}
+	return nil
+}
+
+// resumeFromPause closes the pause window at resumedAt and makes the
+// subscription active, pushing its next billing date back by the whole days
+// spent paused. The billing anchor is kept, so month-end renewals still
+// return to their original day.
+func resumeFromPause(
+	subscriptionRepo *repository.SubscriptionRepository,
+	pauseWindowRepo *repository.PauseWindowRepository,
+	subscription *models.Subscription,
+	pauseWindow *models.PauseWindow,
+	resumedAt time.Time,
+) error {
+	location := subscription.NextBillingDate.Location()
+	if days := models.CalendarDaysBetween(pauseWindow.StartDate.In(location), resumedAt.In(location)); days > 0 {
+		subscription.BillingAnchorDay = subscription.AnchorDay()
+		subscription.NextBillingDate = subscription.NextBillingDate.AddDate(0, 0, days)
+	}
+	subscription.Status = models.SubscriptionStatusActive
+
+	pauseWindow.ResumedAt = &resumedAt
+	if err := pauseWindowRepo.Update(pauseWindow); err != nil {
+		return err
+	}
+	return subscriptionRepo.UpdateFields(subscription, "status", "next_billing_date", "billing_anchor_day")
+}
+
+// SubscriptionRef identifies a subscription in error details.
+type SubscriptionRef struct {
+	ID     models.ULID               `json:"id"`
+	Name   string                    `json:"name"`
+	Status models.SubscriptionStatus `json:"status"`
+}
+
+// inUseError reports that resource cannot be deleted while subscriptions
+// still reference it, listing them in the error details.
+func inUseError(resource string, subscriptions []models.Subscription) error {
+	refs := make([]SubscriptionRef, len(subscriptions))
+	for i, subscription := range subscriptions {
+		refs[i] = SubscriptionRef{ID: subscription.ID, Name: subscription.Name, Status: subscription.Status}
+	}
+	return utils.NewConflictError(
+		fmt.Sprintf("%s is used by %d subscription(s); reassign them with reassignTo", resource, len(subscriptions)),
+		refs,
+	)
 }
@@ -0,0 +1,797 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TransferFormat string
+
+const (
+	TransferFormatCSV  TransferFormat = "csv"
+	TransferFormatJSON TransferFormat = "json"
+)
+
+func IsValidTransferFormat(format TransferFormat) bool {
+	return format == TransferFormatCSV || format == TransferFormatJSON
+}
+
+// maxImportRows bounds one import so it fits in a single transaction.
+const maxImportRows = 1000
+
+type SubscriptionTransferService struct {
+	subscriptionRepo  *repository.SubscriptionRepository
+	categoryRepo      *repository.CategoryRepository
+	currencyRepo      *repository.CurrencyRepository
+	billingCycleRepo  *repository.BillingCycleRepository
+	paymentMethodRepo *repository.PaymentMethodRepository
+	priceChangeRepo   *repository.PriceChangeRepository
+}
+
+// SubscriptionRecord is one subscription in an export file. Currency,
+// category, billing cycle and payment method are referred to by code or
+// name rather than ID so a file can be imported into another account. The
+// billing cycle interval and unit and the payment method type are used
+// when an import creates a missing cycle or payment method. Dates are
+// YYYY-MM-DD.
+type SubscriptionRecord struct {
+	Name                  string   `json:"name"`
+	Description           string   `json:"description"`
+	Amount                float64  `json:"amount"`
+	Currency              string   `json:"currency"`
+	Category              string   `json:"category"`
+	BillingCycle          string   `json:"billingCycle"`
+	BillingCycleInterval  int      `json:"billingCycleInterval"`
+	BillingCycleUnit      string   `json:"billingCycleUnit"`
+	PaymentMethod         string   `json:"paymentMethod"`
+	PaymentMethodType     string   `json:"paymentMethodType"`
+	PaymentMethodLastFour string   `json:"paymentMethodLastFour"`
+	Status                string   `json:"status"`
+	NextBillingDate       string   `json:"nextBillingDate"`
+	ReminderDays          int      `json:"reminderDays"`
+	TrialStartDate        string   `json:"trialStartDate"`
+	TrialEndDate          string   `json:"trialEndDate"`
+	PostTrialAmount       *float64 `json:"postTrialAmount"`
+}
+
+// recordColumns is the CSV header, in the order columns are exported.
+var recordColumns = []string{
+	"name", "description", "amount", "currency", "category",
+	"billingCycle", "billingCycleInterval", "billingCycleUnit",
+	"paymentMethod", "paymentMethodType", "paymentMethodLastFour",
+	"status", "nextBillingDate", "reminderDays",
+	"trialStartDate", "trialEndDate", "postTrialAmount",
+}
+
+// requiredColumns must be present in the header of an imported CSV file.
+var requiredColumns = []string{"name", "amount", "currency", "category", "billingCycle", "paymentMethod"}
+
+// SubscriptionImport reports the outcome of every row of an imported file.
+// Rows that fail validation are skipped; the others are imported together.
+type SubscriptionImport struct {
+	Rows     int                     `json:"rows"`
+	Imported int                     `json:"imported"`
+	Failed   int                     `json:"failed"`
+	Created  ImportedReferences      `json:"created"`
+	Results  []SubscriptionImportRow `json:"results"`
+}
+
+// ImportedReferences are the categories, billing cycles and payment methods
+// an import created because no existing one matched.
+type ImportedReferences struct {
+	Categories     []models.Category      `json:"categories"`
+	BillingCycles  []models.BillingCycle  `json:"billingCycles"`
+	PaymentMethods []models.PaymentMethod `json:"paymentMethods"`
+}
+
+// SubscriptionImportRow is the result of one row. Row is the line number in
+// a CSV file, counting the header, or the position in a JSON array,
+// starting at 1.
+type SubscriptionImportRow struct {
+	Row            int               `json:"row"`
+	Name           string            `json:"name"`
+	SubscriptionID *models.ULID      `json:"subscriptionId,omitempty"`
+	Errors         []*utils.AppError `json:"errors,omitempty"`
+	Warnings       []utils.Warning   `json:"warnings,omitempty"`
+}
+
+// importRow is a decoded row and the errors found while decoding it.
+type importRow struct {
+	row    int
+	record SubscriptionRecord
+	errs   []*utils.AppError
+}
+
+func NewSubscriptionTransferService(
+	subscriptionRepo *repository.SubscriptionRepository,
+	categoryRepo *repository.CategoryRepository,
+	currencyRepo *repository.CurrencyRepository,
+	billingCycleRepo *repository.BillingCycleRepository,
+	paymentMethodRepo *repository.PaymentMethodRepository,
+	priceChangeRepo *repository.PriceChangeRepository,
+) *SubscriptionTransferService {
+	return &SubscriptionTransferService{
+		subscriptionRepo:  subscriptionRepo,
+		categoryRepo:      categoryRepo,
+		currencyRepo:      currencyRepo,
+		billingCycleRepo:  billingCycleRepo,
+		paymentMethodRepo: paymentMethodRepo,
+		priceChangeRepo:   priceChangeRepo,
+	}
+}
+
+// Export writes all of the user's subscriptions, sorted by name, as CSV or
+// as a JSON array. The file can be imported again as is.
+func (s *SubscriptionTransferService) Export(format TransferFormat, userID models.ULID) ([]byte, error) {
+	if !IsValidTransferFormat(format) {
+		return nil, utils.NewValidationError("format", "format must be csv or json")
+	}
+
+	subscriptions, err := s.subscriptionRepo.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(subscriptions, func(i, j int) bool {
+		return strings.ToLower(subscriptions[i].Name) < strings.ToLower(subscriptions[j].Name)
+	})
+
+	records := make([]SubscriptionRecord, 0, len(subscriptions))
+	for i := range subscriptions {
+		records = append(records, newSubscriptionRecord(&subscriptions[i]))
+	}
+
+	if format == TransferFormatJSON {
+		return json.MarshalIndent(records, "", "  ")
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(recordColumns); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if err := writer.Write(record.csvFields()); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newSubscriptionRecord(subscription *models.Subscription) SubscriptionRecord {
+	record := SubscriptionRecord{
+		Name:                  subscription.Name,
+		Description:           subscription.Description,
+		Amount:                subscription.Amount,
+		Currency:              subscription.Currency.Code,
+		Category:              subscription.Category.Name,
+		BillingCycle:          subscription.BillingCycle.Name,
+		BillingCycleInterval:  subscription.BillingCycle.Interval,
+		BillingCycleUnit:      string(subscription.BillingCycle.Unit),
+		PaymentMethod:         subscription.PaymentMethod.Name,
+		PaymentMethodType:     string(subscription.PaymentMethod.Type),
+		PaymentMethodLastFour: subscription.PaymentMethod.LastFour,
+		Status:                string(subscription.Status),
+		NextBillingDate:       subscription.NextBillingDate.Format(time.DateOnly),
+		ReminderDays:          subscription.ReminderDays,
+		PostTrialAmount:       subscription.PostTrialAmount,
+	}
+	if subscription.TrialStartDate != nil {
+		record.TrialStartDate = subscription.TrialStartDate.Format(time.DateOnly)
+	}
+	if subscription.TrialEndDate != nil {
+		record.TrialEndDate = subscription.TrialEndDate.Format(time.DateOnly)
+	}
+	return record
+}
+
+// csvFields returns the record's values in the order of recordColumns.
+func (r SubscriptionRecord) csvFields() []string {
+	postTrialAmount := ""
+	if r.PostTrialAmount != nil {
+		postTrialAmount = strconv.FormatFloat(*r.PostTrialAmount, 'f', 2, 64)
+	}
+	return []string{
+		r.Name, r.Description, strconv.FormatFloat(r.Amount, 'f', 2, 64), r.Currency, r.Category,
+		r.BillingCycle, strconv.Itoa(r.BillingCycleInterval), r.BillingCycleUnit,
+		r.PaymentMethod, r.PaymentMethodType, r.PaymentMethodLastFour,
+		r.Status, r.NextBillingDate, strconv.Itoa(r.ReminderDays),
+		r.TrialStartDate, r.TrialEndDate, postTrialAmount,
+	}
+}
+
+// Import creates a subscription for every valid row of a CSV or JSON file
+// in the format written by Export. References are matched by currency code
+// and by category, billing cycle and payment method name, ignoring case.
+// With createMissing, unknown categories, billing cycles and payment
+// methods are created for the user; otherwise rows using them fail. Rows
+// that fail validation, or share their name with an existing subscription,
+// are reported and skipped without affecting the rest of the file. The
+// valid rows are imported in a single transaction.
+func (s *SubscriptionTransferService) Import(format TransferFormat, r io.Reader, createMissing bool, userID models.ULID) (*SubscriptionImport, error) {
+	var rows []importRow
+	var err error
+	switch format {
+	case TransferFormatCSV:
+		rows, err = decodeCSVRecords(r)
+	case TransferFormatJSON:
+		rows, err = decodeJSONRecords(r)
+	default:
+		return nil, utils.NewValidationError("format", "format must be csv or json")
+	}
+	if err != nil {
+		return nil, utils.NewValidationError("file", err.Error())
+	}
+	if len(rows) == 0 {
+		return nil, utils.NewValidationError("file", "no subscriptions found in file")
+	}
+	if len(rows) > maxImportRows {
+		return nil, utils.NewValidationError("file", fmt.Sprintf("at most %d subscriptions can be imported at once", maxImportRows))
+	}
+
+	importer, err := s.newImporter(createMissing, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SubscriptionImport{
+		Rows: len(rows),
+		Created: ImportedReferences{
+			Categories:     []models.Category{},
+			BillingCycles:  []models.BillingCycle{},
+			PaymentMethods: []models.PaymentMethod{},
+		},
+		Results: make([]SubscriptionImportRow, 0, len(rows)),
+	}
+
+	err = s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+		importer.subscriptionRepo = s.subscriptionRepo.WithTx(tx)
+		importer.priceChangeRepo = s.priceChangeRepo.WithTx(tx)
+		importer.categoryRepo = s.categoryRepo.WithTx(tx)
+		importer.billingCycleRepo = s.billingCycleRepo.WithTx(tx)
+		importer.paymentMethodRepo = s.paymentMethodRepo.WithTx(tx)
+		importer.created = &result.Created
+
+		for _, row := range rows {
+			rowResult := SubscriptionImportRow{
+				Row:    row.row,
+				Name:   row.record.Name,
+				Errors: row.errs,
+			}
+			if len(rowResult.Errors) == 0 {
+				subscription, warnings, errs, err := importer.importRecord(row.record)
+				if err != nil {
+					return err
+				}
+				rowResult.Errors = errs
+				rowResult.Warnings = warnings
+				if subscription != nil {
+					rowResult.SubscriptionID = &subscription.ID
+				}
+			}
+
+			if len(rowResult.Errors) > 0 {
+				result.Failed++
+			} else {
+				result.Imported++
+			}
+			result.Results = append(result.Results, rowResult)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// subscriptionImporter resolves and creates the rows of one import. The
+// lookup maps are keyed by lower-case name, or by currency code, and grow
+// as references are created so later rows reuse them.
+type subscriptionImporter struct {
+	userID        models.ULID
+	createMissing bool
+
+	currencies     map[string]models.ULID
+	categories     map[string]*models.Category
+	billingCycles  map[string]*models.BillingCycle
+	paymentMethods []*models.PaymentMethod
+	names          map[string]bool
+
+	subscriptionRepo  *repository.SubscriptionRepository
+	priceChangeRepo   *repository.PriceChangeRepository
+	categoryRepo      *repository.CategoryRepository
+	billingCycleRepo  *repository.BillingCycleRepository
+	paymentMethodRepo *repository.PaymentMethodRepository
+	created           *ImportedReferences
+}
+
+func (s *SubscriptionTransferService) newImporter(createMissing bool, userID models.ULID) (*subscriptionImporter, error) {
+	importer := &subscriptionImporter{
+		userID:        userID,
+		createMissing: createMissing,
+		currencies:    make(map[string]models.ULID),
+		categories:    make(map[string]*models.Category),
+		billingCycles: make(map[string]*models.BillingCycle),
+		names:         make(map[string]bool),
+	}
+
+	currencies, err := s.currencyRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, currency := range currencies {
+		importer.currencies[currency.Code] = currency.ID
+	}
+
+	categories, err := s.categoryRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range categories {
+		importer.categories[strings.ToLower(categories[i].Name)] = &categories[i]
+	}
+
+	billingCycles, err := s.billingCycleRepo.GetAllForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range billingCycles {
+		importer.billingCycles[strings.ToLower(billingCycles[i].Name)] = &billingCycles[i]
+	}
+
+	paymentMethods, err := s.paymentMethodRepo.GetAllForUser(userID, true)
+	if err != nil {
+		return nil, err
+	}
+	for i := range paymentMethods {
+		importer.paymentMethods = append(importer.paymentMethods, &paymentMethods[i])
+	}
+
+	existing, err := s.subscriptionRepo.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, subscription := range existing {
+		importer.names[strings.ToLower(subscription.Name)] = true
+	}
+
+	return importer, nil
+}
+
+// importRecord validates a record and creates its subscription, along with
+// any missing references. Validation problems are returned as errs and
+// leave the database untouched; err is only set when the database fails.
+func (im *subscriptionImporter) importRecord(record SubscriptionRecord) (subscription *models.Subscription, warnings []utils.Warning, errs []*utils.AppError, err error) {
+	record.Name = strings.TrimSpace(record.Name)
+	if record.Name == "" {
+		errs = append(errs, utils.NewValidationError("name", "name is required"))
+	} else if im.names[strings.ToLower(record.Name)] {
+		errs = append(errs, utils.NewDuplicateEntryError(fmt.Sprintf("subscription '%s'", record.Name)))
+	}
+	if record.Amount < 0 {
+		errs = append(errs, utils.NewValidationError("amount", "amount must not be negative"))
+	}
+	if record.ReminderDays < 0 {
+		errs = append(errs, utils.NewValidationError("reminderDays", "reminderDays must not be negative"))
+	}
+
+	status := models.SubscriptionStatus(strings.ToLower(strings.TrimSpace(record.Status)))
+	if status == "" {
+		status = models.SubscriptionStatusActive
+	} else if !models.IsValidSubscriptionStatus(status) {
+		errs = append(errs, utils.NewValidationError("status", fmt.Sprintf("unknown status '%s'", record.Status)))
+	}
+
+	nextBillingDate, dateErr := parseRecordDate("nextBillingDate", record.NextBillingDate)
+	if dateErr != nil {
+		errs = append(errs, dateErr)
+	}
+	trialStartDate, dateErr := parseRecordDate("trialStartDate", record.TrialStartDate)
+	if dateErr != nil {
+		errs = append(errs, dateErr)
+	}
+	trialEndDate, dateErr := parseRecordDate("trialEndDate", record.TrialEndDate)
+	if dateErr != nil {
+		errs = append(errs, dateErr)
+	}
+
+	currencyID, currencyErr := im.currency(record.Currency)
+	if currencyErr != nil {
+		errs = append(errs, currencyErr)
+	}
+	category, newCategory, categoryErr := im.category(record.Category)
+	if categoryErr != nil {
+		errs = append(errs, categoryErr)
+	}
+	billingCycle, newBillingCycle, billingCycleErr := im.billingCycle(record)
+	if billingCycleErr != nil {
+		errs = append(errs, billingCycleErr)
+	}
+	paymentMethod, newPaymentMethod, paymentMethodErr := im.paymentMethod(record)
+	if paymentMethodErr != nil {
+		errs = append(errs, paymentMethodErr)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs, nil
+	}
+
+	subscription = &models.Subscription{
+		UserID:       im.userID,
+		Name:         record.Name,
+		Description:  record.Description,
+		Amount:       record.Amount,
+		CurrencyID:   currencyID,
+		ReminderDays: record.ReminderDays,
+		Status:       status,
+	}
+	if nextBillingDate != nil {
+		subscription.NextBillingDate = *nextBillingDate
+		subscription.BillingAnchorDay = nextBillingDate.Day()
+	}
+
+	// Paused, past-due and pending cancellations depend on records the
+	// file does not carry, such as the pause window or cancellation date.
+	switch status {
+	case models.SubscriptionStatusPaused, models.SubscriptionStatusPastDue, models.SubscriptionStatusPendingCancellation:
+		subscription.Status = models.SubscriptionStatusActive
+		warnings = append(warnings, utils.Warning{
+			Code:    utils.WarningStatusChanged,
+			Message: fmt.Sprintf("status '%s' cannot be imported; the subscription was imported as active", status),
+		})
+	case models.SubscriptionStatusTrialing:
+		if trialEndDate == nil {
+			return nil, nil, []*utils.AppError{utils.NewValidationError("trialEndDate", "trialEndDate is required for a trialing subscription")}, nil
+		}
+		if err := applyTrial(subscription, trialStartDate, trialEndDate, record.PostTrialAmount); err != nil {
+			return nil, nil, []*utils.AppError{asAppError(err)}, nil
+		}
+	}
+	if err := validateBilling(subscription); err != nil {
+		return nil, nil, []*utils.AppError{asAppError(err)}, nil
+	}
+
+	// Existing references were looked up before the transaction began, so
+	// they are locked and re-read as SubscriptionService.validateReferences
+	// does, in case one has been deleted meanwhile.
+	if !newCategory {
+		if _, err := im.categoryRepo.LockForShare(category.ID); err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return nil, nil, nil, err
+			}
+			errs = append(errs, notFoundField("category", fmt.Sprintf("category '%s'", category.Name)))
+		}
+	}
+	if !newBillingCycle {
+		if _, err := im.billingCycleRepo.LockForShare(billingCycle.ID); err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return nil, nil, nil, err
+			}
+			errs = append(errs, notFoundField("billingCycle", fmt.Sprintf("billing cycle '%s'", billingCycle.Name)))
+		}
+	}
+	if !newPaymentMethod {
+		locked, err := im.paymentMethodRepo.LockForShare(paymentMethod.ID)
+		if err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return nil, nil, nil, err
+			}
+			errs = append(errs, notFoundField("paymentMethod", fmt.Sprintf("payment method '%s'", paymentMethod.Name)))
+		} else if locked.ArchivedAt != nil {
+			errs = append(errs, utils.NewValidationError("paymentMethod", fmt.Sprintf("payment method '%s' is archived", paymentMethod.Name)))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs, nil
+	}
+
+	if newCategory {
+		if err := im.categoryRepo.Create(category); err != nil {
+			return nil, nil, nil, err
+		}
+		im.categories[strings.ToLower(category.Name)] = category
+		im.created.Categories = append(im.created.Categories, *category)
+	}
+	if newBillingCycle {
+		if err := im.billingCycleRepo.Create(billingCycle); err != nil {
+			return nil, nil, nil, err
+		}
+		im.billingCycles[strings.ToLower(billingCycle.Name)] = billingCycle
+		im.created.BillingCycles = append(im.created.BillingCycles, *billingCycle)
+	}
+	if newPaymentMethod {
+		if err := im.paymentMethodRepo.Create(paymentMethod); err != nil {
+			return nil, nil, nil, err
+		}
+		im.paymentMethods = append(im.paymentMethods, paymentMethod)
+		im.created.PaymentMethods = append(im.created.PaymentMethods, *paymentMethod)
+	}
+
+	subscription.CategoryID = category.ID
+	subscription.BillingCycleID = billingCycle.ID
+	subscription.PaymentMethodID = paymentMethod.ID
+	if err := im.subscriptionRepo.Create(subscription); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := im.priceChangeRepo.Create(appliedPriceChange(subscription, subscription.CreatedAt, "")); err != nil {
+		return nil, nil, nil, err
+	}
+	im.names[strings.ToLower(subscription.Name)] = true
+
+	return subscription, warnings, nil, nil
+}
+
+func (im *subscriptionImporter) currency(code string) (models.ULID, *utils.AppError) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return models.ULID{}, utils.NewValidationError("currency", "currency is required")
+	}
+	currencyID, ok := im.currencies[code]
+	if !ok {
+		return models.ULID{}, notFoundField("currency", fmt.Sprintf("currency '%s'", code))
+	}
+	return currencyID, nil
+}
+
+// category returns the category named name, or a new one to create when
+// none exists and missing references may be created.
+func (im *subscriptionImporter) category(name string) (*models.Category, bool, *utils.AppError) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, false, utils.NewValidationError("category", "category is required")
+	}
+	if category, ok := im.categories[strings.ToLower(name)]; ok {
+		return category, false, nil
+	}
+	if !im.createMissing {
+		return nil, false, notFoundField("category", fmt.Sprintf("category '%s'", name))
+	}
+	return &models.Category{Name: name, UserID: &im.userID}, true, nil
+}
+
+// billingCycle returns the billing cycle named in the record, or a new one
+// built from the record's interval and unit.
+func (im *subscriptionImporter) billingCycle(record SubscriptionRecord) (*models.BillingCycle, bool, *utils.AppError) {
+	name := strings.TrimSpace(record.BillingCycle)
+	if name == "" {
+		return nil, false, utils.NewValidationError("billingCycle", "billingCycle is required")
+	}
+	if billingCycle, ok := im.billingCycles[strings.ToLower(name)]; ok {
+		return billingCycle, false, nil
+	}
+	if !im.createMissing {
+		return nil, false, notFoundField("billingCycle", fmt.Sprintf("billing cycle '%s'", name))
+	}
+
+	unit := models.BillingCycleUnit(strings.ToLower(strings.TrimSpace(record.BillingCycleUnit)))
+	if !models.IsValidBillingCycleUnit(unit) || record.BillingCycleInterval < 1 {
+		return nil, false, utils.NewValidationError("billingCycleUnit",
+			fmt.Sprintf("billingCycleInterval and billingCycleUnit are required to create billing cycle '%s'", name))
+	}
+	return &models.BillingCycle{
+		Name:     name,
+		Interval: record.BillingCycleInterval,
+		Unit:     unit,
+		UserID:   &im.userID,
+	}, true, nil
+}
+
+// paymentMethod returns the user's payment method named in the record, also
+// matching the type when one is given, or a new one of that type. Archived
+// methods are not matched, and a row naming only archived ones fails rather
+// than creating a duplicate of them.
+func (im *subscriptionImporter) paymentMethod(record SubscriptionRecord) (*models.PaymentMethod, bool, *utils.AppError) {
+	name := strings.TrimSpace(record.PaymentMethod)
+	if name == "" {
+		return nil, false, utils.NewValidationError("paymentMethod", "paymentMethod is required")
+	}
+	pmType := models.PaymentMethodType(strings.ToLower(strings.TrimSpace(record.PaymentMethodType)))
+	if pmType != "" && !models.IsValidPaymentMethodType(pmType) {
+		return nil, false, utils.NewValidationError("paymentMethodType", fmt.Sprintf("unknown payment method type '%s'", record.PaymentMethodType))
+	}
+
+	var match *models.PaymentMethod
+	archived := false
+	for _, paymentMethod := range im.paymentMethods {
+		if !strings.EqualFold(paymentMethod.Name, name) || (pmType != "" && paymentMethod.Type != pmType) {
+			continue
+		}
+		if paymentMethod.ArchivedAt != nil {
+			archived = true
+			continue
+		}
+		if match != nil {
+			return nil, false, utils.NewValidationError("paymentMethodType",
+				fmt.Sprintf("several payment methods are named '%s'; set paymentMethodType", name))
+		}
+		match = paymentMethod
+	}
+	if match != nil {
+		return match, false, nil
+	}
+	if archived {
+		return nil, false, utils.NewValidationError("paymentMethod", fmt.Sprintf("payment method '%s' is archived", name))
+	}
+	if !im.createMissing {
+		return nil, false, notFoundField("paymentMethod", fmt.Sprintf("payment method '%s'", name))
+	}
+
+	lastFour := strings.TrimSpace(record.PaymentMethodLastFour)
+	if lastFour != "" && len(lastFour) != 4 {
+		return nil, false, utils.NewValidationError("paymentMethodLastFour", "paymentMethodLastFour must be 4 characters")
+	}
+	if pmType == "" {
+		pmType = models.PaymentMethodTypeOther
+	}
+	return &models.PaymentMethod{
+		UserID:   im.userID,
+		Name:     name,
+		Type:     pmType,
+		LastFour: lastFour,
+	}, true, nil
+}
+
+func notFoundField(field, resource string) *utils.AppError {
+	err := utils.NewNotFoundError(resource)
+	err.Field = field
+	return err
+}
+
+// asAppError passes application errors through and wraps anything else as
+// a validation error of the row.
+func asAppError(err error) *utils.AppError {
+	var appErr *utils.AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return utils.NewValidationError("", err.Error())
+}
+
+func parseRecordDate(field, value string) (*time.Time, *utils.AppError) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		if date, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, utils.NewValidationError(field, field+" must be a date like 2024-01-31")
+		}
+	}
+	return &date, nil
+}
+
+// decodeJSONRecords reads a JSON array of records. A row that does not
+// decode is reported as a row error rather than rejecting the file.
+func decodeJSONRecords(r io.Reader) ([]importRow, error) {
+	var messages []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&messages); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of subscriptions: %w", err)
+	}
+
+	rows := make([]importRow, 0, len(messages))
+	for i, message := range messages {
+		row := importRow{row: i + 1}
+		if err := json.Unmarshal(message, &row.record); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				row.errs = append(row.errs, utils.NewValidationError(typeErr.Field,
+					fmt.Sprintf("%s must not be a JSON %s", typeErr.Field, typeErr.Value)))
+			} else {
+				row.errs = append(row.errs, utils.NewValidationError("", "row must be a JSON object"))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// decodeCSVRecords reads a CSV file with a header row naming the columns of
+// recordColumns, in any order. Unknown columns are ignored.
+func decodeCSVRecords(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[strings.ToLower(name)]; !ok {
+			return nil, fmt.Errorf("column %q missing from CSV header", name)
+		}
+	}
+
+	var rows []importRow
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read CSV: %w", err)
+		}
+		if len(fields) == 1 && strings.TrimSpace(fields[0]) == "" {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		row := importRow{row: line}
+
+		field := func(name string) string {
+			index, ok := columns[strings.ToLower(name)]
+			if !ok || index >= len(fields) {
+				return ""
+			}
+			return strings.TrimSpace(fields[index])
+		}
+		number := func(name string) float64 {
+			value := field(name)
+			if value == "" {
+				return 0
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				row.errs = append(row.errs, utils.NewValidationError(name, name+" must be a number"))
+			}
+			return parsed
+		}
+		integer := func(name string) int {
+			value := field(name)
+			if value == "" {
+				return 0
+			}
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				row.errs = append(row.errs, utils.NewValidationError(name, name+" must be a whole number"))
+			}
+			return parsed
+		}
+
+		row.record = SubscriptionRecord{
+			Name:                  field("name"),
+			Description:           field("description"),
+			Amount:                number("amount"),
+			Currency:              field("currency"),
+			Category:              field("category"),
+			BillingCycle:          field("billingCycle"),
+			BillingCycleInterval:  integer("billingCycleInterval"),
+			BillingCycleUnit:      field("billingCycleUnit"),
+			PaymentMethod:         field("paymentMethod"),
+			PaymentMethodType:     field("paymentMethodType"),
+			PaymentMethodLastFour: field("paymentMethodLastFour"),
+			Status:                field("status"),
+			NextBillingDate:       field("nextBillingDate"),
+			ReminderDays:          integer("reminderDays"),
+			TrialStartDate:        field("trialStartDate"),
+			TrialEndDate:          field("trialEndDate"),
+		}
+		if field("postTrialAmount") != "" {
+			postTrialAmount := number("postTrialAmount")
+			row.record.PostTrialAmount = &postTrialAmount
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
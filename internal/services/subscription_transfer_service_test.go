@@ -0,0 +1,284 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"subscription-tracker/internal/models"
+)
+
+// decodedRow is the part of an importRow the tests compare: its position,
+// record and the fields of its errors.
+type decodedRow struct {
+	row       int
+	record    SubscriptionRecord
+	errFields []string
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+func TestDecodeCSVRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []decodedRow
+		wantErr string
+	}{
+		{
+			name: "required columns only",
+			input: "name,amount,currency,category,billingCycle,paymentMethod\n" +
+				"Netflix,15.49,USD,Streaming,Monthly,Visa\n",
+			want: []decodedRow{
+				{row: 2, record: SubscriptionRecord{Name: "Netflix", Amount: 15.49, Currency: "USD", Category: "Streaming", BillingCycle: "Monthly", PaymentMethod: "Visa"}},
+			},
+		},
+		{
+			name: "columns in any order and case with unknown ones ignored",
+			input: "\ufeffPaymentMethod, Category,Name,notes,billingcycle,Currency,AMOUNT,reminderDays,postTrialAmount,trialEndDate\n" +
+				"Amex, Software,\"Editor, Pro\",ignored,Yearly,EUR,99,7,120,2024-06-30\n",
+			want: []decodedRow{
+				{row: 2, record: SubscriptionRecord{
+					Name: "Editor, Pro", Amount: 99, Currency: "EUR", Category: "Software", BillingCycle: "Yearly",
+					PaymentMethod: "Amex", ReminderDays: 7, TrialEndDate: "2024-06-30", PostTrialAmount: floatPtr(120),
+				}},
+			},
+		},
+		{
+			name: "blank lines are skipped and rows numbered by line",
+			input: "name,amount,currency,category,billingCycle,paymentMethod\n" +
+				"A,1,USD,C,Monthly,Visa\n\n" +
+				"B,2,USD,C,Monthly,Visa\n",
+			want: []decodedRow{
+				{row: 2, record: SubscriptionRecord{Name: "A", Amount: 1, Currency: "USD", Category: "C", BillingCycle: "Monthly", PaymentMethod: "Visa"}},
+				{row: 4, record: SubscriptionRecord{Name: "B", Amount: 2, Currency: "USD", Category: "C", BillingCycle: "Monthly", PaymentMethod: "Visa"}},
+			},
+		},
+		{
+			name: "invalid numbers are row errors",
+			input: "name,amount,currency,category,billingCycle,paymentMethod,billingCycleInterval,reminderDays\n" +
+				"A,ten,USD,C,Monthly,Visa,1.5,\n",
+			want: []decodedRow{
+				{row: 2, record: SubscriptionRecord{Name: "A", Currency: "USD", Category: "C", BillingCycle: "Monthly", PaymentMethod: "Visa"}, errFields: []string{"amount", "billingCycleInterval"}},
+			},
+		},
+		{
+			name: "short rows leave missing fields empty",
+			input: "name,amount,currency,category,billingCycle,paymentMethod,description\n" +
+				"A,1,USD\n",
+			want: []decodedRow{
+				{row: 2, record: SubscriptionRecord{Name: "A", Amount: 1, Currency: "USD"}},
+			},
+		},
+		{
+			name:    "missing required column",
+			input:   "name,amount,currency,category,paymentMethod\nA,1,USD,C,Visa\n",
+			wantErr: `column "billingCycle" missing from CSV header`,
+		},
+		{
+			name:    "empty file",
+			input:   "",
+			wantErr: "read CSV header",
+		},
+		{
+			name:    "malformed quotes",
+			input:   "name,amount,currency,category,billingCycle,paymentMethod\n\"A,1,USD,C,Monthly,Visa\n",
+			wantErr: "read CSV",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := decodeCSVRecords(strings.NewReader(tt.input))
+			checkDecodedRows(t, rows, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestDecodeJSONRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []decodedRow
+		wantErr string
+	}{
+		{
+			name:  "records",
+			input: `[{"name":"Netflix","amount":15.49,"currency":"USD","reminderDays":3},{"name":"Gym","postTrialAmount":30,"unknown":true}]`,
+			want: []decodedRow{
+				{row: 1, record: SubscriptionRecord{Name: "Netflix", Amount: 15.49, Currency: "USD", ReminderDays: 3}},
+				{row: 2, record: SubscriptionRecord{Name: "Gym", PostTrialAmount: floatPtr(30)}},
+			},
+		},
+		{
+			name:  "wrong field type is a row error",
+			input: `[{"name":"Netflix","amount":"15.49"},{"name":"Gym"}]`,
+			want: []decodedRow{
+				{row: 1, record: SubscriptionRecord{Name: "Netflix"}, errFields: []string{"amount"}},
+				{row: 2, record: SubscriptionRecord{Name: "Gym"}},
+			},
+		},
+		{
+			name:  "row that is not an object",
+			input: `["Netflix"]`,
+			want: []decodedRow{
+				{row: 1, errFields: []string{""}},
+			},
+		},
+		{
+			name:  "empty array",
+			input: `[]`,
+			want:  []decodedRow{},
+		},
+		{
+			name:    "not an array",
+			input:   `{"name":"Netflix"}`,
+			wantErr: "expected a JSON array of subscriptions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := decodeJSONRecords(strings.NewReader(tt.input))
+			checkDecodedRows(t, rows, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestSubscriptionRecordRoundTrip(t *testing.T) {
+	records := []SubscriptionRecord{
+		{
+			Name: "Netflix", Description: "Family plan, 4K", Amount: 22.99, Currency: "USD", Category: "Streaming",
+			BillingCycle: "Monthly", BillingCycleInterval: 1, BillingCycleUnit: "month",
+			PaymentMethod: "Visa", PaymentMethodType: "credit_card", PaymentMethodLastFour: "4242",
+			Status: "active", NextBillingDate: "2024-06-15", ReminderDays: 3,
+		},
+		{
+			Name: "Editor", Amount: 0, Currency: "EUR", Category: "Software",
+			BillingCycle: "Yearly", BillingCycleInterval: 1, BillingCycleUnit: "year",
+			PaymentMethod: "PayPal", PaymentMethodType: "paypal",
+			Status: "trial", NextBillingDate: "2024-07-01",
+			TrialStartDate: "2024-06-01", TrialEndDate: "2024-07-01", PostTrialAmount: floatPtr(99.5),
+		},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		writer.Write(recordColumns)
+		for _, record := range records {
+			writer.Write(record.csvFields())
+		}
+		writer.Flush()
+
+		rows, err := decodeCSVRecords(&buf)
+		checkRoundTrip(t, rows, err, records)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(records)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := decodeJSONRecords(bytes.NewReader(data))
+		checkRoundTrip(t, rows, err, records)
+	})
+}
+
+func TestImporterPaymentMethod(t *testing.T) {
+	archivedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	visa := &models.PaymentMethod{Name: "Visa", Type: models.PaymentMethodTypeCreditCard}
+	oldVisa := &models.PaymentMethod{Name: "Visa", Type: models.PaymentMethodTypeDebitCard, ArchivedAt: &archivedAt}
+	oldAmex := &models.PaymentMethod{Name: "Amex", Type: models.PaymentMethodTypeCreditCard, ArchivedAt: &archivedAt}
+
+	tests := []struct {
+		name          string
+		record        SubscriptionRecord
+		createMissing bool
+		want          *models.PaymentMethod
+		wantNew       bool
+		wantErr       string
+	}{
+		{"active method matched", SubscriptionRecord{PaymentMethod: "visa"}, false, visa, false, ""},
+		{"archived namesake skipped", SubscriptionRecord{PaymentMethod: "Visa", PaymentMethodType: "credit_card"}, false, visa, false, ""},
+		{"only archived match", SubscriptionRecord{PaymentMethod: "Visa", PaymentMethodType: "debit_card"}, false, nil, false, "archived"},
+		{"archived not recreated", SubscriptionRecord{PaymentMethod: "Amex"}, true, nil, false, "archived"},
+		{"missing created", SubscriptionRecord{PaymentMethod: "PayPal"}, true, nil, true, ""},
+		{"missing not created", SubscriptionRecord{PaymentMethod: "PayPal"}, false, nil, false, "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			importer := &subscriptionImporter{
+				createMissing:  tt.createMissing,
+				paymentMethods: []*models.PaymentMethod{visa, oldVisa, oldAmex},
+			}
+			got, isNew, appErr := importer.paymentMethod(tt.record)
+			if tt.wantErr != "" {
+				if appErr == nil || !strings.Contains(appErr.Message, tt.wantErr) {
+					t.Fatalf("paymentMethod() error = %v, want it to contain %q", appErr, tt.wantErr)
+				}
+				return
+			}
+			if appErr != nil {
+				t.Fatalf("paymentMethod() error = %v", appErr)
+			}
+			if isNew != tt.wantNew {
+				t.Errorf("paymentMethod() new = %v, want %v", isNew, tt.wantNew)
+			}
+			if tt.want != nil && got != tt.want {
+				t.Errorf("paymentMethod() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func checkDecodedRows(t *testing.T, rows []importRow, err error, want []decodedRow, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("error = %v, want it to contain %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("decoded %d rows, want %d", len(rows), len(want))
+	}
+	for i, row := range rows {
+		var errFields []string
+		for _, appErr := range row.errs {
+			errFields = append(errFields, appErr.Field)
+		}
+		got := decodedRow{row: row.row, record: row.record, errFields: errFields}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("row %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func checkRoundTrip(t *testing.T, rows []importRow, err error, want []SubscriptionRecord) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("decoded %d rows, want %d", len(rows), len(want))
+	}
+	for i, row := range rows {
+		if len(row.errs) > 0 {
+			t.Errorf("row %d errors: %v", row.row, row.errs)
+		}
+		if !reflect.DeepEqual(row.record, want[i]) {
+			t.Errorf("row %d = %+v, want %+v", row.row, row.record, want[i])
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const trialBatchSize = 100
+
+type TrialService struct {
+	subscriptionRepo *repository.SubscriptionRepository
+	billingCycleRepo *repository.BillingCycleRepository
+	paymentRepo      *repository.PaymentRepository
+	priceChangeRepo  *repository.PriceChangeRepository
+}
+
+func NewTrialService(
+	subscriptionRepo *repository.SubscriptionRepository,
+	billingCycleRepo *repository.BillingCycleRepository,
+	paymentRepo *repository.PaymentRepository,
+	priceChangeRepo *repository.PriceChangeRepository,
+) *TrialService {
+	return &TrialService{
+		subscriptionRepo: subscriptionRepo,
+		billingCycleRepo: billingCycleRepo,
+		paymentRepo:      paymentRepo,
+		priceChangeRepo:  priceChangeRepo,
+	}
+}
+
+// EndingSoon lists the user's trials that end within the given number of
+// days, including ended trials that have not been converted yet.
+func (s *TrialService) EndingSoon(userID models.ULID, days int) ([]models.Subscription, error) {
+	if days < 0 {
+		return nil, utils.NewValidationError("days", "days must not be negative")
+	}
+	return s.subscriptionRepo.GetTrialsEndingBefore(userID, time.Now().AddDate(0, 0, days))
+}
+
+// ConvertEnded moves every trialing subscription whose trial has ended onto
+// its paid plan: the post-trial amount becomes the price, the first charge is
+// recorded on the trial end date, the next billing date moves one cycle on
+// and the subscription becomes active. Subscriptions cancelled during the
+// trial are no longer trialing and are left alone. It returns the number of
+// trials converted.
+func (s *TrialService) ConvertEnded(now time.Time) (int, error) {
+	converted := 0
+	for {
+		var batch int
+		err := s.subscriptionRepo.Transaction(func(tx *gorm.DB) error {
+			subscriptionRepo := s.subscriptionRepo.WithTx(tx)
+			paymentRepo := s.paymentRepo.WithTx(tx)
+			priceChangeRepo := s.priceChangeRepo.WithTx(tx)
+
+			subscriptions, err := subscriptionRepo.LockEndedTrials(now, trialBatchSize)
+			if err != nil {
+				return err
+			}
+			batch = len(subscriptions)
+
+			billingCycles, err := billingCyclesFor(s.billingCycleRepo, subscriptions)
+			if err != nil {
+				return err
+			}
+
+			for i := range subscriptions {
+				subscription := &subscriptions[i]
+				trialEnd := *subscription.TrialEndDate
+				billingCycle := billingCycles[subscription.BillingCycleID]
+
+				if subscription.PostTrialAmount != nil {
+					subscription.Amount = *subscription.PostTrialAmount
+				}
+				subscription.TrialConvertedAt = &now
+				subscription.Status = models.SubscriptionStatusActive
+				subscription.BillingAnchorDay = trialEnd.Day()
+				subscription.NextBillingDate = billingCycle.NextDate(trialEnd, subscription.BillingAnchorDay)
+
+				firstCharge := &models.Payment{
+					UserID:          subscription.UserID,
+					SubscriptionID:  subscription.ID,
+					PaymentMethodID: subscription.PaymentMethodID,
+					CurrencyID:      subscription.CurrencyID,
+					Amount:          subscription.Amount,
+					PaidAt:          trialEnd,
+					Source:          models.PaymentSourceTrialConversion,
+				}
+				if err := paymentRepo.Create(firstCharge); err != nil {
+					return err
+				}
+
+				err := subscriptionRepo.UpdateFields(subscription,
+					"amount", "status", "trial_converted_at", "billing_anchor_day", "next_billing_date")
+				if err != nil {
+					return err
+				}
+				if err := priceChangeRepo.Create(appliedPriceChange(subscription, trialEnd, "trial ended")); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return converted, err
+		}
+
+		converted += batch
+		if batch < trialBatchSize {
+			return converted, nil
+		}
+	}
+}
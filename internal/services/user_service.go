@@ -1,8 +1,319 @@
 package services
 
-// WIP: User management services to be implemented
-// Planned features:
-// - Get user profile
-// - Update user profile
-// - Change password
-// - Delete account
+import (
+	"regexp"
+	"strings"
+	"subscription-tracker/internal/auth"
+	"subscription-tracker/internal/config"
+	"subscription-tracker/internal/models"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/utils"
+	"time"
+	_ "time/tzdata" // Validate time zones on hosts without a zoneinfo database
+
+	"gorm.io/gorm"
+)
+
+// purgeBatchSize is the number of deleted accounts purged per transaction;
+// each purge touches every table with user data.
+const purgeBatchSize = 10
+
+// localePattern accepts BCP 47 language tags such as "en", "en-US" and
+// "zh-Hant-TW".
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+type UserService struct {
+	userRepo         *repository.UserRepository
+	currencyRepo     *repository.CurrencyRepository
+	refreshTokenRepo *repository.RefreshTokenRepository
+	config           *config.Config
+}
+
+// UserProfile is the user as returned by the API, without the password hash
+// and other internal fields.
+type UserProfile struct {
+	ID             models.ULID      `json:"id"`
+	Name           string           `json:"name"`
+	Email          string           `json:"email"`
+	BaseCurrencyID *models.ULID     `json:"baseCurrencyId"`
+	BaseCurrency   *models.Currency `json:"baseCurrency,omitempty"`
+	Timezone       string           `json:"timezone"`
+	Locale         string           `json:"locale"`
+	IsAdmin        bool             `json:"isAdmin"`
+	CreatedAt      time.Time        `json:"createdAt"`
+}
+
+type SetBaseCurrencyRequest struct {
+	CurrencyID *string `json:"currencyId"` // null stops converting amounts
+}
+
+type UpdateProfileRequest struct {
+	Name           string  `json:"name" binding:"required"`
+	Email          string  `json:"email" binding:"required,email"`
+	BaseCurrencyID *string `json:"baseCurrencyId"` // null stops converting amounts
+	Timezone       string  `json:"timezone" binding:"required"`
+	Locale         string  `json:"locale" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,min=6"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// AccountDeletion tells the user until when a deleted account can be
+// restored.
+type AccountDeletion struct {
+	DeletesAt time.Time `json:"deletesAt"`
+}
+
+func NewUserService(
+	userRepo *repository.UserRepository,
+	currencyRepo *repository.CurrencyRepository,
+	refreshTokenRepo *repository.RefreshTokenRepository,
+	cfg *config.Config,
+) *UserService {
+	return &UserService{
+		userRepo:         userRepo,
+		currencyRepo:     currencyRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		config:           cfg,
+	}
+}
+
+func newUserProfile(user *models.User) *UserProfile {
+	return &UserProfile{
+		ID:             user.ID,
+		Name:           user.Name,
+		Email:          user.Email,
+		BaseCurrencyID: user.BaseCurrencyID,
+		BaseCurrency:   user.BaseCurrency,
+		Timezone:       user.Timezone,
+		Locale:         user.Locale,
+		IsAdmin:        user.IsAdmin,
+		CreatedAt:      user.CreatedAt,
+	}
+}
+
+func (s *UserService) GetProfile(userID models.ULID) (*UserProfile, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.BaseCurrencyID != nil {
+		if user.BaseCurrency, err = s.currencyRepo.GetByID(*user.BaseCurrencyID); err != nil {
+			return nil, err
+		}
+	}
+
+	return newUserProfile(user), nil
+}
+
+// UpdateProfile replaces the user's name, email, base currency, time zone
+// and locale.
+func (s *UserService) UpdateProfile(userID models.ULID, req *UpdateProfileRequest) (*UserProfile, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email != user.Email {
+		exists, err := s.userRepo.EmailExists(email)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, utils.NewValidationError("email", "email already registered")
+		}
+	}
+
+	if _, err := time.LoadLocation(req.Timezone); err != nil || req.Timezone == "Local" {
+		return nil, utils.NewValidationError("timezone", "timezone must be an IANA time zone such as Europe/Berlin")
+	}
+	if !localePattern.MatchString(req.Locale) {
+		return nil, utils.NewValidationError("locale", "locale must be a language tag such as en-US")
+	}
+
+	user.BaseCurrencyID = nil
+	user.BaseCurrency = nil
+	if req.BaseCurrencyID != nil {
+		var currencyID models.ULID
+		if err := currencyID.UnmarshalJSON([]byte(`"` + *req.BaseCurrencyID + `"`)); err != nil {
+			return nil, utils.NewValidationError("baseCurrencyId", "invalid format")
+		}
+		currency, err := s.currencyRepo.GetByID(currencyID)
+		if err != nil {
+			return nil, utils.NewNotFoundError("currency")
+		}
+		user.BaseCurrencyID = &currency.ID
+		user.BaseCurrency = currency
+	}
+
+	user.Name = req.Name
+	user.Email = email
+	user.Timezone = req.Timezone
+	user.Locale = req.Locale
+
+	if err := s.userRepo.UpdateFields(user, "name", "email", "base_currency_id", "timezone", "locale"); err != nil {
+		return nil, err
+	}
+
+	return newUserProfile(user), nil
+}
+
+// ChangePassword replaces the user's password after checking the current
+// one, and revokes every token issued so far. The response carries a new
+// token so the caller stays signed in.
+func (s *UserService) ChangePassword(userID models.ULID, req *ChangePasswordRequest) (*AuthResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := auth.VerifyPassword(user.PasswordHash, req.CurrentPassword); err != nil {
+		return nil, utils.NewValidationError("currentPassword", "current password is incorrect")
+	}
+
+	hashedPassword, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user.PasswordHash = hashedPassword
+
+	var response *AuthResponse
+	err = s.userRepo.Transaction(func(tx *gorm.DB) error {
+		userRepo := s.userRepo.WithTx(tx)
+		if err := userRepo.UpdateFields(user, "password_hash"); err != nil {
+			return err
+		}
+		// The replacement token carries the new version
+		if err := userRepo.RevokeTokens(user, now); err != nil {
+			return err
+		}
+		refreshTokenRepo := s.refreshTokenRepo.WithTx(tx)
+		if err := refreshTokenRepo.RevokeAllForUser(user.ID, now); err != nil {
+			return err
+		}
+		response, err = issueTokens(refreshTokenRepo, s.config, user, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// SetBaseCurrency sets the currency the user's totals are converted to.
+func (s *UserService) SetBaseCurrency(userID models.ULID, req *SetBaseCurrencyRequest) (*models.Currency, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var currency *models.Currency
+	if req.CurrencyID != nil {
+		var currencyID models.ULID
+		if err := currencyID.UnmarshalJSON([]byte(`"` + *req.CurrencyID + `"`)); err != nil {
+			return nil, utils.NewValidationError("currencyId", "invalid format")
+		}
+		currency, err = s.currencyRepo.GetByID(currencyID)
+		if err != nil {
+			return nil, utils.NewNotFoundError("currency")
+		}
+		user.BaseCurrencyID = &currency.ID
+	} else {
+		user.BaseCurrencyID = nil
+	}
+
+	if err := s.userRepo.UpdateFields(user, "base_currency_id"); err != nil {
+		return nil, err
+	}
+
+	return currency, nil
+}
+
+// RequestDeletion schedules the account for deletion once the grace period
+// has passed and signs the user out everywhere. Logging in again with
+// restoreAccount set cancels the deletion.
+func (s *UserService) RequestDeletion(userID models.ULID, req *DeleteAccountRequest) (*AccountDeletion, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := auth.VerifyPassword(user.PasswordHash, req.Password); err != nil {
+		return nil, utils.NewValidationError("password", "password is incorrect")
+	}
+
+	now := time.Now()
+	deletesAt := now.Add(s.config.Account.DeletionGracePeriod)
+	user.DeletesAt = &deletesAt
+	err = s.userRepo.Transaction(func(tx *gorm.DB) error {
+		userRepo := s.userRepo.WithTx(tx)
+		if err := userRepo.UpdateFields(user, "deletes_at"); err != nil {
+			return err
+		}
+		if err := userRepo.RevokeTokens(user, now); err != nil {
+			return err
+		}
+		return s.refreshTokenRepo.WithTx(tx).RevokeAllForUser(user.ID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountDeletion{DeletesAt: deletesAt}, nil
+}
+
+// PurgeDeleted permanently deletes the accounts whose grace period ended by
+// now, together with all of their data. It returns the number of accounts
+// purged.
+func (s *UserService) PurgeDeleted(now time.Time) (int, error) {
+	purged := 0
+	for {
+		var batch int
+		err := s.userRepo.Transaction(func(tx *gorm.DB) error {
+			userRepo := s.userRepo.WithTx(tx)
+
+			users, err := userRepo.LockDueForDeletion(now, purgeBatchSize)
+			if err != nil {
+				return err
+			}
+			batch = len(users)
+
+			for _, user := range users {
+				if err := userRepo.Purge(user.ID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return purged, err
+		}
+
+		purged += batch
+		if batch < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+func (s *UserService) getUser(id models.ULID) (*models.User, error) {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utils.NewNotFoundError("user")
+		}
+		return nil, err
+	}
+	return user, nil
+}
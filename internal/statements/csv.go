@@ -0,0 +1,228 @@
+package statements
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CSVMapping names the header columns of a CSV export. Column names are
+// matched case-insensitively. Either Amount or at least one of Debit and
+// Credit must be set.
+type CSVMapping struct {
+	Date        string
+	Description string
+	Amount      string // Signed amount, negative for charges unless DebitsPositive
+	Debit       string // Unsigned charge amount
+	Credit      string // Unsigned refund or deposit amount
+	Currency    string // Optional
+	// Go reference layout of the dates, e.g. "02/01/2006". Common layouts
+	// are tried when empty.
+	DateFormat string
+	// DebitsPositive is set for card exports that list charges as positive
+	// amounts in the Amount column.
+	DebitsPositive bool
+	Delimiter      string // Defaults to a comma
+}
+
+var defaultDateFormats = []string{
+	time.DateOnly,
+	"2006/01/02",
+	"01/02/2006",
+	"1/2/2006",
+	"02.01.2006",
+	"2 Jan 2006",
+	"02 Jan 2006",
+	"Jan 2, 2006",
+	time.RFC3339,
+}
+
+// ParseCSV reads a CSV export with a header row using mapping.
+func ParseCSV(r io.Reader, mapping CSVMapping) (*Statement, error) {
+	if mapping.Date == "" || mapping.Description == "" {
+		return nil, errors.New("date and description columns are required")
+	}
+	if mapping.Amount == "" && mapping.Debit == "" && mapping.Credit == "" {
+		return nil, errors.New("an amount column or debit and credit columns are required")
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if mapping.Delimiter != "" {
+		delimiter, size := utf8.DecodeRuneInString(mapping.Delimiter)
+		if size != len(mapping.Delimiter) {
+			return nil, errors.New("delimiter must be a single character")
+		}
+		reader.Comma = delimiter
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	column := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		index, ok := columns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return -1, fmt.Errorf("column %q not found in CSV header", name)
+		}
+		return index, nil
+	}
+
+	var indexes [6]int
+	for i, name := range []string{mapping.Date, mapping.Description, mapping.Amount, mapping.Debit, mapping.Credit, mapping.Currency} {
+		if indexes[i], err = column(name); err != nil {
+			return nil, err
+		}
+	}
+	dateColumn, descriptionColumn, amountColumn, debitColumn, creditColumn, currencyColumn :=
+		indexes[0], indexes[1], indexes[2], indexes[3], indexes[4], indexes[5]
+
+	field := func(record []string, index int) string {
+		if index < 0 || index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[index])
+	}
+
+	statement := &Statement{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		date, err := parseDate(field(record, dateColumn), mapping.DateFormat)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var amount float64
+		if amountColumn >= 0 {
+			if amount, err = parseAmount(field(record, amountColumn)); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if mapping.DebitsPositive {
+				amount = -amount
+			}
+		} else {
+			debit, credit := field(record, debitColumn), field(record, creditColumn)
+			if debit != "" {
+				value, err := parseAmount(debit)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+				amount -= absolute(value)
+			}
+			if credit != "" {
+				value, err := parseAmount(credit)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+				amount += absolute(value)
+			}
+		}
+
+		statement.Transactions = append(statement.Transactions, Transaction{
+			Date:        date,
+			Description: field(record, descriptionColumn),
+			Amount:      amount,
+			Currency:    strings.ToUpper(field(record, currencyColumn)),
+		})
+	}
+
+	if len(statement.Transactions) == 0 {
+		return nil, errors.New("no transactions found in CSV")
+	}
+	return statement, nil
+}
+
+func parseDate(value, layout string) (time.Time, error) {
+	if layout != "" {
+		date, err := time.Parse(layout, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+		return date, nil
+	}
+	for _, layout := range defaultDateFormats {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q; set the date format", value)
+}
+
+// parseAmount reads amounts like "-12.99", "1,234.56", "$9.99", "(9.99)" and
+// "12,99".
+func parseAmount(value string) (float64, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == ',', r == '-', r == '(':
+			return r
+		}
+		return -1
+	}, value)
+
+	negative := false
+	if strings.HasPrefix(cleaned, "(") {
+		negative = true
+		cleaned = strings.TrimPrefix(cleaned, "(")
+	}
+	if strings.HasPrefix(cleaned, "-") {
+		negative = !negative
+		cleaned = strings.TrimPrefix(cleaned, "-")
+	}
+	if strings.HasSuffix(cleaned, "-") {
+		negative = !negative
+		cleaned = strings.TrimSuffix(cleaned, "-")
+	}
+
+	// The last separator is the decimal point when both are present; a lone
+	// comma is a decimal comma if it is followed by exactly two digits.
+	lastDot, lastComma := strings.LastIndex(cleaned, "."), strings.LastIndex(cleaned, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0 && lastComma > lastDot:
+		cleaned = strings.ReplaceAll(cleaned, ".", "")
+		cleaned = strings.Replace(cleaned, ",", ".", 1)
+	case lastComma >= 0 && lastDot < 0 && len(cleaned)-lastComma == 3 && strings.Count(cleaned, ",") == 1:
+		cleaned = strings.Replace(cleaned, ",", ".", 1)
+	default:
+		cleaned = strings.ReplaceAll(cleaned, ",", "")
+	}
+
+	amount, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+func absolute(value float64) float64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,138 @@
+package statements
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{"-12.99", -12.99, false},
+		{"12.99", 12.99, false},
+		{"1,234.56", 1234.56, false},
+		{"-1,234.56", -1234.56, false},
+		{"1.234,56", 1234.56, false},
+		{"12,99", 12.99, false},
+		{"1,234", 1234, false},
+		{"$9.99", 9.99, false},
+		{"-$9.99", -9.99, false},
+		{"(9.99)", -9.99, false},
+		{"9.99-", -9.99, false},
+		{"EUR 15,00", 15, false},
+		{" 7 ", 7, false},
+		{"", 0, true},
+		{"n/a", 0, true},
+		{"1.2.3", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAmount(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseAmount(%q) = %v, want an error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseAmount(%q) error = %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAmount(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		mapping CSVMapping
+		want    []Transaction
+		wantErr string
+	}{
+		{
+			name:    "signed amounts",
+			input:   "Date,Description,Amount\n2024-05-02,NETFLIX.COM,-15.49\n2024-05-03,Salary,2500\n",
+			mapping: CSVMapping{Date: "date", Description: "description", Amount: "amount"},
+			want: []Transaction{
+				{Date: date(2024, 5, 2), Description: "NETFLIX.COM", Amount: -15.49},
+				{Date: date(2024, 5, 3), Description: "Salary", Amount: 2500},
+			},
+		},
+		{
+			name:    "positive debits with currency and date format",
+			input:   "Posted;Payee;Value;Ccy\n02/05/2024;Spotify;10,99;eur\n",
+			mapping: CSVMapping{Date: "Posted", Description: "Payee", Amount: "Value", Currency: "Ccy", DateFormat: "02/01/2006", DebitsPositive: true, Delimiter: ";"},
+			want: []Transaction{
+				{Date: date(2024, 5, 2), Description: "Spotify", Amount: -10.99, Currency: "EUR"},
+			},
+		},
+		{
+			name:    "debit and credit columns",
+			input:   "Date,Description,Debit,Credit\n2024-05-02,Gym,30.00,\n2024-05-03,Refund,,5.00\n",
+			mapping: CSVMapping{Date: "Date", Description: "Description", Debit: "Debit", Credit: "Credit"},
+			want: []Transaction{
+				{Date: date(2024, 5, 2), Description: "Gym", Amount: -30},
+				{Date: date(2024, 5, 3), Description: "Refund", Amount: 5},
+			},
+		},
+		{
+			name:    "missing column",
+			input:   "Date,Description,Amount\n2024-05-02,Gym,-30\n",
+			mapping: CSVMapping{Date: "Date", Description: "Payee", Amount: "Amount"},
+			wantErr: `column "Payee" not found`,
+		},
+		{
+			name:    "invalid date",
+			input:   "Date,Description,Amount\n2024-05-02,Gym,-30\nyesterday,Gym,-30\n",
+			mapping: CSVMapping{Date: "Date", Description: "Description", Amount: "Amount"},
+			wantErr: `line 3: invalid date "yesterday"`,
+		},
+		{
+			name:    "no amount column",
+			input:   "Date,Description\n",
+			mapping: CSVMapping{Date: "Date", Description: "Description"},
+			wantErr: "an amount column",
+		},
+		{
+			name:    "header only",
+			input:   "Date,Description,Amount\n",
+			mapping: CSVMapping{Date: "Date", Description: "Description", Amount: "Amount"},
+			wantErr: "no transactions found in CSV",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSV(strings.NewReader(tt.input), tt.mapping)
+			checkTransactions(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func checkTransactions(t *testing.T, got *Statement, err error, want []Transaction, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("error = %v, want it to contain %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got.Transactions, want) {
+		t.Errorf("transactions = %+v, want %+v", got.Transactions, want)
+	}
+}
@@ -0,0 +1,113 @@
+package statements
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseOFX reads the bank and credit card transactions of an OFX or QFX
+// file. Both the SGML flavour of OFX 1.x, where leaf elements are not
+// closed, and the XML of OFX 2.x are accepted.
+func ParseOFX(r io.Reader) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read OFX: %w", err)
+	}
+	content := string(data)
+	start := strings.Index(strings.ToUpper(content), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("no <OFX> element found")
+	}
+
+	statement := &Statement{}
+	var current *Transaction
+	var currency, memo string
+
+	// Every element starts with "<"; a segment is the tag name up to ">"
+	// followed by the element's value, if it has one.
+	for _, segment := range strings.Split(content[start:], "<")[1:] {
+		tag, value, found := strings.Cut(segment, ">")
+		if !found {
+			continue
+		}
+		tag = strings.ToUpper(strings.TrimSpace(tag))
+		value = html.UnescapeString(strings.TrimSpace(value))
+
+		switch tag {
+		case "STMTTRN":
+			current = &Transaction{}
+			memo = ""
+		case "/STMTTRN":
+			if current == nil {
+				continue
+			}
+			if current.Date.IsZero() {
+				return nil, errors.New("transaction without DTPOSTED")
+			}
+			if current.Description == "" {
+				current.Description = memo
+			}
+			if current.Currency == "" {
+				current.Currency = currency
+			}
+			statement.Transactions = append(statement.Transactions, *current)
+			current = nil
+		case "CURDEF":
+			currency = strings.ToUpper(value)
+			if statement.Currency == "" {
+				statement.Currency = currency
+			}
+		case "ACCTID":
+			if statement.AccountID == "" {
+				statement.AccountID = value
+			}
+		}
+
+		if current == nil {
+			continue
+		}
+		switch tag {
+		case "DTPOSTED":
+			date, err := parseOFXDate(value)
+			if err != nil {
+				return nil, err
+			}
+			current.Date = date
+		case "TRNAMT":
+			amount, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid amount %q", value)
+			}
+			current.Amount = amount
+		case "NAME":
+			current.Description = value
+		case "MEMO":
+			memo = value
+		case "CURSYM":
+			current.Currency = strings.ToUpper(value)
+		}
+	}
+
+	if len(statement.Transactions) == 0 {
+		return nil, errors.New("no transactions found in OFX")
+	}
+	return statement, nil
+}
+
+// parseOFXDate reads dates like "20240502", "20240502120000" and
+// "20240502120000.000[-5:EST]". Only the day is kept.
+func parseOFXDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return date, nil
+}
@@ -0,0 +1,109 @@
+package statements
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		want         []Transaction
+		wantCurrency string
+		wantAccount  string
+		wantErr      string
+	}{
+		{
+			name: "SGML",
+			input: `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX>
+<CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CURDEF>usd
+<CCACCTFROM><ACCTID>4111111111111234</CCACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240502120000.000[-5:EST]
+<TRNAMT>-15.49
+<NAME>NETFLIX.COM
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240503
+<TRNAMT>-9,99
+<MEMO>Spotify P1234 &amp; family
+<CURRENCY><CURSYM>eur</CURRENCY>
+</STMTTRN>
+</BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>`,
+			want: []Transaction{
+				{Date: date(2024, 5, 2), Description: "NETFLIX.COM", Amount: -15.49, Currency: "USD"},
+				{Date: date(2024, 5, 3), Description: "Spotify P1234 & family", Amount: -9.99, Currency: "EUR"},
+			},
+			wantCurrency: "USD",
+			wantAccount:  "4111111111111234",
+		},
+		{
+			name: "XML",
+			input: `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>GBP</CURDEF>
+<BANKACCTFROM><ACCTID>12345678</ACCTID></BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20240101</DTPOSTED><TRNAMT>-7.00</TRNAMT><NAME>Gym</NAME></STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`,
+			want: []Transaction{
+				{Date: date(2024, 1, 1), Description: "Gym", Amount: -7, Currency: "GBP"},
+			},
+			wantCurrency: "GBP",
+			wantAccount:  "12345678",
+		},
+		{
+			name:    "not OFX",
+			input:   "Date,Description,Amount\n",
+			wantErr: "no <OFX> element found",
+		},
+		{
+			name:    "missing date",
+			input:   "<OFX><STMTTRN><TRNAMT>-1.00<NAME>Gym</STMTTRN></OFX>",
+			wantErr: "transaction without DTPOSTED",
+		},
+		{
+			name:    "invalid date",
+			input:   "<OFX><STMTTRN><DTPOSTED>2024-05<TRNAMT>-1.00</STMTTRN></OFX>",
+			wantErr: `invalid date "2024-05"`,
+		},
+		{
+			name:    "invalid amount",
+			input:   "<OFX><STMTTRN><DTPOSTED>20240502<TRNAMT>ten</STMTTRN></OFX>",
+			wantErr: `invalid amount "ten"`,
+		},
+		{
+			name:    "no transactions",
+			input:   "<OFX><CURDEF>USD</OFX>",
+			wantErr: "no transactions found in OFX",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOFX(strings.NewReader(tt.input))
+			checkTransactions(t, got, err, tt.want, tt.wantErr)
+			if err != nil {
+				return
+			}
+			if got.Currency != tt.wantCurrency {
+				t.Errorf("Currency = %q, want %q", got.Currency, tt.wantCurrency)
+			}
+			if got.AccountID != tt.wantAccount {
+				t.Errorf("AccountID = %q, want %q", got.AccountID, tt.wantAccount)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package statements
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// amountTolerance is how far, relative to the smallest, the charges of one
+// subscription may differ in amount, allowing for small price changes and
+// currency conversion.
+const amountTolerance = 0.15
+
+// RecurringCharge is a merchant charging a similar amount at a regular
+// interval. Unit and Interval match the billing cycle units.
+type RecurringCharge struct {
+	Merchant    string    `json:"merchant"`    // Display name derived from the descriptions
+	Description string    `json:"description"` // Latest description as it appears on the statement
+	Amount      float64   `json:"amount"`      // Latest charge, positive
+	Currency    string    `json:"currency"`
+	Unit        string    `json:"unit"`
+	Interval    int       `json:"interval"`
+	Occurrences int       `json:"occurrences"`
+	FirstDate   time.Time `json:"firstDate"`
+	LastDate    time.Time `json:"lastDate"`
+}
+
+// period is a billing interval and the range of days between two charges
+// that counts as that interval.
+type period struct {
+	unit             string
+	interval         int
+	minDays, maxDays float64
+	minOccurrences   int
+}
+
+// periods are tried in order; weekly charges need three occurrences so two
+// unrelated purchases a week apart are not taken for a subscription.
+var periods = []period{
+	{"week", 1, 6, 8, 3},
+	{"week", 2, 13, 15, 3},
+	{"month", 1, 27, 33, 2},
+	{"month", 3, 85, 97, 2},
+	{"month", 6, 175, 190, 2},
+	{"year", 1, 355, 376, 2},
+}
+
+// noiseWords are dropped from descriptions when grouping charges by
+// merchant.
+var noiseWords = map[string]bool{
+	"POS": true, "PURCHASE": true, "DEBIT": true, "CARD": true, "RECURRING": true,
+	"PAYMENT": true, "ACH": true, "VISA": true, "MASTERCARD": true, "WWW": true,
+	"COM": true, "NET": true, "INC": true, "LLC": true, "LTD": true, "THE": true,
+}
+
+// Detect finds the recurring charges among the debits of transactions.
+// Transactions without a currency are taken to be in defaultCurrency.
+//
+// Each merchant yields at most one charge: when its debits form several
+// series, e.g. after a price change beyond amountTolerance, the series
+// charged most recently is kept. A series whose last charge is more than one
+// period, tolerance included, before the last transaction of the statement
+// has stopped and is left out.
+func Detect(transactions []Transaction, defaultCurrency string) []RecurringCharge {
+	type groupKey struct{ merchant, currency string }
+	groups := make(map[groupKey][]Transaction)
+	var end time.Time
+	for _, transaction := range transactions {
+		if transaction.Date.After(end) {
+			end = transaction.Date
+		}
+		if transaction.Amount >= 0 {
+			continue
+		}
+		merchant := merchantKey(transaction.Description)
+		if merchant == "" {
+			continue
+		}
+		currency := transaction.Currency
+		if currency == "" {
+			currency = defaultCurrency
+		}
+		key := groupKey{merchant, strings.ToUpper(currency)}
+		groups[key] = append(groups[key], transaction)
+	}
+
+	latest := make(map[string]RecurringCharge)
+	for key, group := range groups {
+		for _, cluster := range clusterByAmount(group) {
+			charge, ok := detectPeriod(cluster, end)
+			if !ok {
+				continue
+			}
+			charge.Merchant = displayName(key.merchant)
+			charge.Currency = key.currency
+			if kept, seen := latest[key.merchant]; seen && !laterCharge(charge, kept) {
+				continue
+			}
+			latest[key.merchant] = charge
+		}
+	}
+
+	charges := make([]RecurringCharge, 0, len(latest))
+	for _, charge := range latest {
+		charges = append(charges, charge)
+	}
+	sort.Slice(charges, func(i, j int) bool {
+		return charges[i].Merchant < charges[j].Merchant
+	})
+	return charges
+}
+
+// laterCharge reports whether a was charged after b. Series last charged on
+// the same day are ordered by amount, then currency, so the result does not
+// depend on map order.
+func laterCharge(a, b RecurringCharge) bool {
+	if !a.LastDate.Equal(b.LastDate) {
+		return a.LastDate.After(b.LastDate)
+	}
+	if a.Amount != b.Amount {
+		return a.Amount > b.Amount
+	}
+	return a.Currency < b.Currency
+}
+
+// clusterByAmount splits one merchant's charges into groups of similar
+// amounts, so two plans from the same merchant are told apart.
+func clusterByAmount(transactions []Transaction) [][]Transaction {
+	sort.Slice(transactions, func(i, j int) bool {
+		return transactions[i].Amount > transactions[j].Amount // Smallest charge first
+	})
+
+	var clusters [][]Transaction
+	var smallest float64
+	for _, transaction := range transactions {
+		amount := -transaction.Amount
+		if len(clusters) == 0 || amount > smallest*(1+amountTolerance) {
+			clusters = append(clusters, nil)
+			smallest = amount
+		}
+		clusters[len(clusters)-1] = append(clusters[len(clusters)-1], transaction)
+	}
+	return clusters
+}
+
+// detectPeriod checks whether every gap between consecutive charges fits
+// the same period, and that the last charge is no more than one period
+// before end.
+func detectPeriod(transactions []Transaction, end time.Time) (RecurringCharge, bool) {
+	sort.Slice(transactions, func(i, j int) bool {
+		return transactions[i].Date.Before(transactions[j].Date)
+	})
+
+	// Several charges on the same day count once
+	dates := []time.Time{transactions[0].Date}
+	for _, transaction := range transactions[1:] {
+		if !sameDay(transaction.Date, dates[len(dates)-1]) {
+			dates = append(dates, transaction.Date)
+		}
+	}
+	if len(dates) < 2 {
+		return RecurringCharge{}, false
+	}
+
+	for _, candidate := range periods {
+		if len(dates) < candidate.minOccurrences {
+			continue
+		}
+		matches := true
+		for i := 1; i < len(dates); i++ {
+			days := dates[i].Sub(dates[i-1]).Hours() / 24
+			if days < candidate.minDays || days > candidate.maxDays {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		if end.Sub(dates[len(dates)-1]).Hours()/24 > candidate.maxDays {
+			return RecurringCharge{}, false
+		}
+
+		latest := transactions[len(transactions)-1]
+		return RecurringCharge{
+			Description: latest.Description,
+			Amount:      math.Round(-latest.Amount*100) / 100,
+			Unit:        candidate.unit,
+			Interval:    candidate.interval,
+			Occurrences: len(dates),
+			FirstDate:   dates[0],
+			LastDate:    dates[len(dates)-1],
+		}, true
+	}
+	return RecurringCharge{}, false
+}
+
+// merchantKey normalizes a description so the charges of one merchant group
+// together, e.g. "NETFLIX.COM 866-579-7172 CA" becomes "NETFLIX CA".
+// Words with digits, which tend to be references that change every month,
+// are dropped.
+func merchantKey(description string) string {
+	words := strings.FieldsFunc(strings.ToUpper(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var kept []string
+	for _, word := range words {
+		if noiseWords[word] || strings.IndexFunc(word, unicode.IsDigit) >= 0 || len(word) < 2 {
+			continue
+		}
+		kept = append(kept, word)
+		if len(kept) == 3 {
+			break
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+func displayName(merchant string) string {
+	words := strings.Fields(strings.ToLower(merchant))
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
@@ -0,0 +1,115 @@
+package statements
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func debit(day time.Time, description string, amount float64) Transaction {
+	return Transaction{Date: day, Description: description, Amount: -amount}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		want         []RecurringCharge
+	}{
+		{
+			name: "monthly charges with changing references",
+			transactions: []Transaction{
+				debit(date(2024, 1, 15), "NETFLIX.COM 866-579-7172 CA", 15.49),
+				debit(date(2024, 2, 15), "NETFLIX.COM 866-579-7172 CA", 15.49),
+				debit(date(2024, 3, 14), "NETFLIX.COM 866-123-4567 CA", 15.49),
+				{Date: date(2024, 3, 20), Description: "Salary", Amount: 2500},
+			},
+			want: []RecurringCharge{
+				{Merchant: "Netflix Ca", Description: "NETFLIX.COM 866-123-4567 CA", Amount: 15.49, Currency: "USD", Unit: "month", Interval: 1, Occurrences: 3, FirstDate: date(2024, 1, 15), LastDate: date(2024, 3, 14)},
+			},
+		},
+		{
+			name: "weekly charges need three occurrences",
+			transactions: []Transaction{
+				debit(date(2024, 3, 1), "Coffee Club", 4.50),
+				debit(date(2024, 3, 8), "Coffee Club", 4.50),
+				debit(date(2024, 3, 8), "Bakery", 3.20),
+			},
+			want: nil,
+		},
+		{
+			name: "irregular charges",
+			transactions: []Transaction{
+				debit(date(2024, 1, 3), "Grocer", 45.10),
+				debit(date(2024, 1, 19), "Grocer", 44.20),
+				debit(date(2024, 2, 28), "Grocer", 46.00),
+			},
+			want: nil,
+		},
+		{
+			name: "one charge per merchant from the latest series",
+			transactions: []Transaction{
+				debit(date(2024, 4, 1), "Cloud Storage", 2.99),
+				debit(date(2024, 5, 1), "Cloud Storage", 2.99),
+				debit(date(2024, 6, 1), "Cloud Storage", 2.99),
+				debit(date(2024, 4, 10), "Cloud Storage", 9.99),
+				debit(date(2024, 5, 10), "Cloud Storage", 9.99),
+				debit(date(2024, 6, 10), "Cloud Storage", 9.99),
+			},
+			want: []RecurringCharge{
+				{Merchant: "Cloud Storage", Description: "Cloud Storage", Amount: 9.99, Currency: "USD", Unit: "month", Interval: 1, Occurrences: 3, FirstDate: date(2024, 4, 10), LastDate: date(2024, 6, 10)},
+			},
+		},
+		{
+			name: "price rise starts a new series",
+			transactions: []Transaction{
+				debit(date(2023, 3, 5), "Music Service", 99),
+				debit(date(2024, 3, 5), "Music Service", 99),
+				debit(date(2024, 3, 20), "Bakery", 3.20),
+				debit(date(2024, 4, 5), "Video Service", 8),
+				debit(date(2024, 5, 5), "Video Service", 8),
+				debit(date(2024, 6, 5), "Video Service", 10),
+				debit(date(2024, 7, 5), "Video Service", 10),
+			},
+			want: []RecurringCharge{
+				{Merchant: "Music Service", Description: "Music Service", Amount: 99, Currency: "USD", Unit: "year", Interval: 1, Occurrences: 2, FirstDate: date(2023, 3, 5), LastDate: date(2024, 3, 5)},
+				{Merchant: "Video Service", Description: "Video Service", Amount: 10, Currency: "USD", Unit: "month", Interval: 1, Occurrences: 2, FirstDate: date(2024, 6, 5), LastDate: date(2024, 7, 5)},
+			},
+		},
+		{
+			name: "stopped series are left out",
+			transactions: []Transaction{
+				debit(date(2024, 1, 2), "Gym", 30),
+				debit(date(2024, 2, 2), "Gym", 30),
+				debit(date(2024, 3, 2), "Gym", 30),
+				debit(date(2024, 4, 20), "Bakery", 3.20),
+			},
+			want: nil,
+		},
+		{
+			name: "currencies are grouped separately",
+			transactions: []Transaction{
+				{Date: date(2024, 1, 9), Description: "VPN", Amount: -5, Currency: "eur"},
+				{Date: date(2024, 2, 9), Description: "VPN", Amount: -5, Currency: "EUR"},
+				debit(date(2024, 1, 20), "News", 4),
+				debit(date(2024, 2, 20), "News", 4),
+			},
+			want: []RecurringCharge{
+				{Merchant: "News", Description: "News", Amount: 4, Currency: "USD", Unit: "month", Interval: 1, Occurrences: 2, FirstDate: date(2024, 1, 20), LastDate: date(2024, 2, 20)},
+				{Merchant: "Vpn", Description: "VPN", Amount: 5, Currency: "EUR", Unit: "month", Interval: 1, Occurrences: 2, FirstDate: date(2024, 1, 9), LastDate: date(2024, 2, 9)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(tt.transactions, "USD")
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
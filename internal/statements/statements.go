@@ -0,0 +1,67 @@
+// Package statements parses bank and card statement exports and detects the
+// recurring charges in them.
+package statements
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format identifies the layout of a statement file.
+type Format string
+
+const (
+	// FormatCSV is a delimited export whose columns are described by a
+	// CSVMapping.
+	FormatCSV Format = "csv"
+	// FormatOFX is an Open Financial Exchange file, SGML (1.x) or XML (2.x).
+	FormatOFX Format = "ofx"
+	// FormatQFX is Quicken's variant of OFX and is parsed the same way.
+	FormatQFX Format = "qfx"
+)
+
+// Transaction is a single statement line. Amount is negative for money
+// leaving the account.
+type Transaction struct {
+	Date        time.Time
+	Description string
+	Amount      float64
+	Currency    string // ISO 4217 code, empty if the statement does not say
+}
+
+// Statement is the content of one statement file.
+type Statement struct {
+	Transactions []Transaction
+	Currency     string // Default currency of the account, if known
+	AccountID    string // Account or card number, if known
+}
+
+func IsValidFormat(format Format) bool {
+	return format == FormatCSV || format == FormatOFX || format == FormatQFX
+}
+
+// DetectFormat guesses the format of a file from its name.
+func DetectFormat(filename string) Format {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ofx":
+		return FormatOFX
+	case ".qfx":
+		return FormatQFX
+	}
+	return FormatCSV
+}
+
+// Parse reads a statement in the given format. mapping is only used for
+// CSV files.
+func Parse(format Format, r io.Reader, mapping CSVMapping) (*Statement, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(r, mapping)
+	case FormatOFX, FormatQFX:
+		return ParseOFX(r)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
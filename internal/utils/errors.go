@@ -2,9 +2,10 @@ package utils
 
 // AppError is the base error type for our application
 type AppError struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Field   string `json:"field,omitempty"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Field   string      `json:"field,omitempty"`
+	Details interface{} `json:"details,omitempty"` // Returned as the response data
 }
 
 func (e *AppError) Error() string {
@@ -19,6 +20,7 @@ const (
 	CodeBadRequest    = "BAD_REQUEST"
 	CodeValidation    = "VALIDATION_ERROR"
 	CodeDuplicate     = "DUPLICATE_ENTRY"
+	CodeConflict      = "CONFLICT"
 	CodeInternalError = "INTERNAL_ERROR"
 )
 
@@ -73,6 +75,16 @@ func NewDuplicateEntryError(resource string) *AppError {
 	}
 }
 
+// NewConflictError reports a request that cannot be carried out in the
+// current state, with details describing what is in the way.
+func NewConflictError(message string, details interface{}) *AppError {
+	return &AppError{
+		Code:    CodeConflict,
+		Message: message,
+		Details: details,
+	}
+}
+
 func NewInternalError(message string) *AppError {
 	return &AppError{
 		Code:    CodeInternalError,
@@ -101,3 +113,10 @@ func IsForbidden(err error) bool {
 	}
 	return false
 }
+
+func IsConflict(err error) bool {
+	if appErr, ok := err.(*AppError); ok {
+		return appErr.Code == CodeConflict
+	}
+	return false
+}
@@ -19,6 +19,12 @@ func HandleHttpError(c *gin.Context, err error) {
 			c.JSON(http.StatusUnauthorized, ErrorResponse(err.Error()))
 		case CodeDuplicate:
 			c.JSON(http.StatusConflict, ErrorResponse(err.Error()))
+		case CodeConflict:
+			c.JSON(http.StatusConflict, Response{
+				Success: false,
+				Error:   err.Error(),
+				Data:    appErr.Details,
+			})
 		default:
 			c.JSON(http.StatusInternalServerError, ErrorResponse(err.Error()))
 		}
@@ -1,12 +1,26 @@
 package utils
 
 type Response struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	Success  bool        `json:"success"`
+	Message  string      `json:"message,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Warnings []Warning   `json:"warnings,omitempty"`
 }
 
+// Warning flags something the client should know about a request that
+// nonetheless succeeded.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Warning codes
+const (
+	WarningOverBudget    = "OVER_BUDGET"
+	WarningStatusChanged = "STATUS_CHANGED"
+)
+
 // SuccessResponse creates a success response with optional data
 func SuccessResponse(data interface{}) Response {
 	return Response{
@@ -15,6 +29,16 @@ func SuccessResponse(data interface{}) Response {
 	}
 }
 
+// SuccessWarningResponse creates a success response with data and any
+// warnings raised while handling the request
+func SuccessWarningResponse(data interface{}, warnings []Warning) Response {
+	return Response{
+		Success:  true,
+		Data:     data,
+		Warnings: warnings,
+	}
+}
+
 // SuccessMessageResponse creates a success response with a message
 func SuccessMessageResponse(message string) Response {
 	return Response{
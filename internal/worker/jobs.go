@@ -0,0 +1,191 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"subscription-tracker/internal/config"
+	"subscription-tracker/internal/notify"
+	"subscription-tracker/internal/repository"
+	"subscription-tracker/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// New builds a runner with all background jobs registered.
+func New(db *gorm.DB, cfg *config.Config) (*Runner, error) {
+	notifier, err := notify.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize repositories
+	userRepo := repository.NewUserRepository(db)
+	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	billingCycleRepo := repository.NewBillingCycleRepository(db)
+	paymentRepo := repository.NewPaymentRepository(db)
+	reminderRepo := repository.NewReminderRepository(db)
+	priceChangeRepo := repository.NewPriceChangeRepository(db)
+	currencyRepo := repository.NewCurrencyRepository(db)
+	cancellationRepo := repository.NewCancellationRepository(db)
+	pauseWindowRepo := repository.NewPauseWindowRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	paymentMethodRepo := repository.NewPaymentMethodRepository(db)
+	exchangeRateRepo := repository.NewExchangeRateRepository(db)
+	budgetRepo := repository.NewBudgetRepository(db)
+	analyticsRepo := repository.NewAnalyticsRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+
+	// Initialize services
+	subscriptionService := services.NewSubscriptionService(
+		subscriptionRepo,
+		categoryRepo,
+		currencyRepo,
+		billingCycleRepo,
+		paymentMethodRepo,
+		priceChangeRepo,
+		cancellationRepo,
+		pauseWindowRepo,
+		userRepo,
+		exchangeRateRepo,
+		budgetRepo,
+		analyticsRepo,
+	)
+	renewalService := services.NewRenewalService(subscriptionRepo, billingCycleRepo, paymentRepo, priceChangeRepo)
+	trialService := services.NewTrialService(subscriptionRepo, billingCycleRepo, paymentRepo, priceChangeRepo)
+	priceService := services.NewPriceService(priceChangeRepo, subscriptionRepo, currencyRepo)
+	cancellationService := services.NewCancellationService(cancellationRepo, subscriptionRepo, billingCycleRepo)
+	reminderService := services.NewReminderService(reminderRepo, subscriptionRepo, userRepo, notifier)
+	userService := services.NewUserService(userRepo, currencyRepo, refreshTokenRepo, cfg)
+	authService := services.NewAuthService(userRepo, refreshTokenRepo, cfg)
+	dataExportService := services.NewDataExportService(
+		dataExportRepo,
+		userRepo,
+		currencyRepo,
+		subscriptionRepo,
+		paymentMethodRepo,
+		categoryRepo,
+		billingCycleRepo,
+		paymentRepo,
+		priceChangeRepo,
+		reminderRepo,
+		budgetRepo,
+		pauseWindowRepo,
+		cancellationRepo,
+	)
+
+	runner := NewRunner()
+	runner.Register(Job{
+		Name:     "price-changes",
+		Interval: cfg.Worker.RenewalInterval,
+		Run: func(ctx context.Context, now time.Time) error {
+			applied, err := priceService.ApplyDue(now)
+			if applied > 0 {
+				log.Printf("Applied %d scheduled price changes", applied)
+			}
+			return err
+		},
+	})
+	runner.Register(Job{
+		Name:     "trials",
+		Interval: cfg.Worker.RenewalInterval,
+		Run: func(ctx context.Context, now time.Time) error {
+			converted, err := trialService.ConvertEnded(now)
+			if converted > 0 {
+				log.Printf("Converted %d trials", converted)
+			}
+			return err
+		},
+	})
+	runner.Register(Job{
+		Name:     "pauses",
+		Interval: cfg.Worker.RenewalInterval,
+		Run: func(ctx context.Context, now time.Time) error {
+			processed, err := subscriptionService.ApplyPauses(now)
+			if processed > 0 {
+				log.Printf("Started or ended %d pause windows", processed)
+			}
+			return err
+		},
+	})
+	runner.Register(Job{
+		Name:     "renewals",
+		Interval: cfg.Worker.RenewalInterval,
+		Run: func(ctx context.Context, now time.Time) error {
+			renewed, err := renewalService.RenewDue(now)
+			if renewed > 0 {
+				log.Printf("Renewed %d subscriptions", renewed)
+			}
+			return err
+		},
+	})
+	runner.Register(Job{
+		Name:     "cancellations",
+		Interval: cfg.Worker.RenewalInterval,
+		Run: func(ctx context.Context, now time.Time) error {
+			cancelled, err := cancellationService.ExpireDue(now)
+			if cancelled > 0 {
+				log.Printf("Cancelled %d subscriptions at period end", cancelled)
+			}
+			return err
+		},
+	})
+	runner.Register(Job{
+		Name:     "reminders",
+		Interval: cfg.Worker.ReminderInterval,
+		Run: func(ctx context.Context, now time.Time) error {
+			if _, err := reminderService.Schedule(now); err != nil {
+				return err
+			}
+			sent, err := reminderService.Dispatch(ctx, now)
+			if sent > 0 {
+				log.Printf("Sent %d reminders", sent)
+			}
+			return err
+		},
+	})
+	runner.Register(Job{
+		Name:     "account-purge",
+		Interval: cfg.Worker.PurgeInterval,
+		Run: func(ctx context.Context, now time.Time) error {
+			purged, err := userService.PurgeDeleted(now)
+			if purged > 0 {
+				log.Printf("Purged %d deleted accounts", purged)
+			}
+			return err
+		},
+	})
+	runner.Register(Job{
+		Name:     "refresh-tokens",
+		Interval: cfg.Worker.TokenCleanupInterval,
+		Run: func(ctx context.Context, now time.Time) error {
+			deleted, err := authService.DeleteExpiredRefreshTokens(now)
+			if deleted > 0 {
+				log.Printf("Deleted %d expired refresh tokens", deleted)
+			}
+			return err
+		},
+	})
+	runner.Register(Job{
+		Name:     "data-exports",
+		Interval: cfg.Worker.ExportInterval,
+		Run: func(ctx context.Context, now time.Time) error {
+			built, err := dataExportService.BuildPending(now)
+			if built > 0 {
+				log.Printf("Built %d data exports", built)
+			}
+			if err != nil {
+				return err
+			}
+			deleted, err := dataExportService.DeleteExpired(now)
+			if deleted > 0 {
+				log.Printf("Deleted %d expired data exports", deleted)
+			}
+			return err
+		},
+	})
+
+	return runner, nil
+}
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work that runs periodically.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context, now time.Time) error
+}
+
+// Runner executes registered jobs on their own interval until its context is
+// cancelled. Jobs must be safe to run from several instances at once.
+type Runner struct {
+	jobs []Job
+}
+
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+func (r *Runner) Register(job Job) {
+	r.jobs = append(r.jobs, job)
+}
+
+// Run starts every job immediately and then on each tick, blocking until ctx
+// is done and all in-flight runs have returned.
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range r.jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			r.loop(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+}
+
+func (r *Runner) loop(ctx context.Context, job Job) {
+	log.Printf("Starting job %s (every %s)", job.Name, job.Interval)
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job.Run(ctx, time.Now()); err != nil {
+			log.Printf("Job %s failed: %v", job.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Stopping job %s", job.Name)
+			return
+		case <-ticker.C:
+		}
+	}
+}